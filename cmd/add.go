@@ -11,7 +11,7 @@ import (
 
 var addCmd = &cobra.Command{
 	Use:   "add [fileName] [url] [numTimes]",
-	Short: "Creates txt file in current directory",
+	Short: "Appends a line to a txt file in the workspace",
 	Args: func(cmd *cobra.Command, args []string) error {
         if len(args) < 2 {
             return fmt.Errorf("requires at least two arguments")
@@ -30,21 +30,38 @@ var addCmd = &cobra.Command{
             times = args[2]
         }
 		urlAdd := args[1] + " " + times
+		if _, err := parseLine(urlAdd); err != nil {
+			fmt.Println("Invalid line:", err)
+			return
+		}
 		
 		if !strings.HasSuffix(fileName, ".txt") {
 			fileName = fileName + ".txt"
 		}
 
-		filePath := filepath.Join("executable", fileName)
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			fmt.Println("Error creating directory:", err)
+			return
+		}
+
+		// Start on a new line if the last one wasn't terminated.
+		if content, err := os.ReadFile(filePath); err == nil && len(content) > 0 && content[len(content)-1] != '\n' {
+			urlAdd = "\n" + urlAdd
+		}
 
-		file, err := os.Create(filePath)
+		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 		if err != nil {
-			fmt.Println("Error creating file:", err)
+			fmt.Println("Error opening file:", err)
 			return
 		}
 		defer file.Close()
 
-		_, err = file.WriteString(urlAdd)
+		_, err = file.WriteString(urlAdd + "\n")
 		if err != nil {
 			fmt.Println("Error writing to file:", err)
 			return
@@ -0,0 +1,406 @@
+// cmd/auth.go
+//
+// Authentication helpers. Each scheme is a RoundTripper wrapped around
+// the base transport, so it applies to every command and every request
+// of a stress run: Digest answers the server's challenge, and OAuth2
+// client-credentials tokens are refreshed before they expire.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type authOptions struct {
+	Basic  string
+	Bearer string
+	Digest string
+
+	OAuth2TokenURL     string
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	OAuth2Scopes       string
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.Basic, "basic", "", "USER:PASS for HTTP Basic authentication")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.Bearer, "bearer", "", "Bearer token sent as Authorization header")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.Digest, "digest", "", "USER:PASS for HTTP Digest authentication")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.OAuth2TokenURL, "oauth2-token-url", "", "OAuth2 token endpoint for the client-credentials grant")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.OAuth2ClientID, "oauth2-client-id", "", "OAuth2 client ID")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.OAuth2ClientSecret, "oauth2-client-secret", "", "OAuth2 client secret")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Auth.OAuth2Scopes, "oauth2-scopes", "", "Space separated OAuth2 scopes")
+}
+
+// authorizationHeader turns a raw --auth-token value into an
+// Authorization header, adding the Bearer scheme unless one is given.
+func authorizationHeader(token string) string {
+	if token == "" || strings.Contains(token, " ") {
+		return token
+	}
+	return "Bearer " + token
+}
+
+func splitCredentials(flag, raw string) (string, string, error) {
+	user, pass, ok := strings.Cut(raw, ":")
+	if !ok {
+		return "", "", fmt.Errorf("--%s expects USER:PASS", flag)
+	}
+	return user, pass, nil
+}
+
+// wrap returns rt with the configured authentication applied, or rt
+// itself when no scheme is set.
+func (opts authOptions) wrap(rt http.RoundTripper) (http.RoundTripper, error) {
+	schemes := 0
+	for _, set := range []bool{opts.Basic != "", opts.Bearer != "", opts.Digest != "", opts.OAuth2TokenURL != ""} {
+		if set {
+			schemes++
+		}
+	}
+	if schemes > 1 {
+		return nil, fmt.Errorf("--basic, --bearer, --digest and --oauth2-token-url are mutually exclusive")
+	}
+
+	var authed http.RoundTripper
+	switch {
+	case opts.Basic != "":
+		user, pass, err := splitCredentials("basic", opts.Basic)
+		if err != nil {
+			return nil, err
+		}
+		authed = &basicAuth{rt: rt, user: user, pass: pass}
+	case opts.Bearer != "":
+		authed = &headerAuth{rt: rt, value: "Bearer " + opts.Bearer}
+	case opts.Digest != "":
+		user, pass, err := splitCredentials("digest", opts.Digest)
+		if err != nil {
+			return nil, err
+		}
+		authed = &digestAuth{rt: rt, user: user, pass: pass}
+	case opts.OAuth2TokenURL != "":
+		source := oauth2Source(opts, rt)
+		// Fetch up front so a bad client secret fails before the run.
+		if _, err := source.token(); err != nil {
+			return nil, err
+		}
+		authed = &oauth2Auth{rt: rt, source: source}
+	default:
+		return rt, nil
+	}
+	return &originOnly{creds: authed, rt: rt}, nil
+}
+
+// withHeader returns a copy of req with key set, leaving req untouched
+// as the RoundTripper contract requires.
+func withHeader(req *http.Request, key, value string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set(key, value)
+	return clone
+}
+
+// rewind returns a copy of req whose body can be sent again, for
+// retrying after an authentication challenge.
+func rewind(req *http.Request) (*http.Request, bool) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	clone.Body = body
+	return clone, true
+}
+
+type basicAuth struct {
+	rt         http.RoundTripper
+	user, pass string
+}
+
+func (t *basicAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.SetBasicAuth(t.user, t.pass)
+	return t.rt.RoundTrip(clone)
+}
+
+type headerAuth struct {
+	rt    http.RoundTripper
+	value string
+}
+
+func (t *headerAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.rt.RoundTrip(withHeader(req, "Authorization", t.value))
+}
+
+// Digest (RFC 7616). The last challenge is kept so later requests can
+// authenticate up front instead of taking a 401 each time.
+type digestAuth struct {
+	rt         http.RoundTripper
+	user, pass string
+
+	mu        sync.Mutex
+	challenge map[string]string
+	nc        int
+}
+
+func parseDigestChallenge(headers []string) (map[string]string, bool) {
+	for _, header := range headers {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		challenge := make(map[string]string)
+		for params != "" {
+			var key, value string
+			key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+			if strings.HasPrefix(params, `"`) {
+				end := strings.Index(params[1:], `"`)
+				if end < 0 {
+					break
+				}
+				value, params = params[1:end+1], params[end+2:]
+			} else {
+				value, params, _ = strings.Cut(params, ",")
+			}
+			challenge[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+		if challenge["nonce"] != "" {
+			return challenge, true
+		}
+	}
+	return nil, false
+}
+
+func digestHash(algorithm string) func() hash.Hash {
+	if strings.HasPrefix(strings.ToUpper(algorithm), "SHA-256") {
+		return sha256.New
+	}
+	return md5.New
+}
+
+func (t *digestAuth) authorization(req *http.Request) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.challenge == nil {
+		return "", false
+	}
+	t.nc++
+	c := t.challenge
+	newHash := digestHash(c["algorithm"])
+	h := func(s string) string {
+		sum := newHash()
+		io.WriteString(sum, s)
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	uri := req.URL.RequestURI()
+	nc := fmt.Sprintf("%08x", t.nc)
+	cnonce := randomHex(8)
+	ha1 := h(t.user + ":" + c["realm"] + ":" + t.pass)
+	if strings.HasSuffix(strings.ToLower(c["algorithm"]), "-sess") {
+		ha1 = h(ha1 + ":" + c["nonce"] + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	qop := ""
+	for _, offered := range strings.Split(c["qop"], ",") {
+		if strings.TrimSpace(offered) == "auth" {
+			qop = "auth"
+		}
+	}
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, c["nonce"], nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + c["nonce"] + ":" + ha2)
+	}
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		t.user, c["realm"], c["nonce"], uri, response)
+	if c["algorithm"] != "" {
+		header += ", algorithm=" + c["algorithm"]
+	}
+	if qop != "" {
+		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if c["opaque"] != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, c["opaque"])
+	}
+	return header, true
+}
+
+func (t *digestAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	send := req
+	if header, ok := t.authorization(req); ok {
+		send = withHeader(req, "Authorization", header)
+	}
+	resp, err := t.rt.RoundTrip(send)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge, ok := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if !ok {
+		return resp, nil
+	}
+	retry, ok := rewind(req)
+	if !ok {
+		return resp, nil
+	}
+	drainAndClose(resp.Body)
+
+	t.mu.Lock()
+	t.challenge = challenge
+	t.nc = 0
+	t.mu.Unlock()
+
+	header, _ := t.authorization(retry)
+	retry.Header.Set("Authorization", header)
+	return t.rt.RoundTrip(retry)
+}
+
+// OAuth2 client credentials (RFC 6749 section 4.4).
+type tokenSource struct {
+	opts   authOptions
+	client *http.Client
+
+	mu     sync.Mutex
+	access string
+	expiry time.Time
+}
+
+// Tokens are refreshed this long before they expire so requests in
+// flight don't race the expiry.
+const tokenRefreshMargin = 30 * time.Second
+
+var oauth2Sources struct {
+	sync.Mutex
+	sources map[string]*tokenSource
+}
+
+// oauth2Source returns the token source for opts, shared across every
+// transport in the process so each client fetches one token at a time.
+func oauth2Source(opts authOptions, rt http.RoundTripper) *tokenSource {
+	key := strings.Join([]string{opts.OAuth2TokenURL, opts.OAuth2ClientID, opts.OAuth2Scopes}, "\x00")
+	oauth2Sources.Lock()
+	defer oauth2Sources.Unlock()
+	if oauth2Sources.sources == nil {
+		oauth2Sources.sources = make(map[string]*tokenSource)
+	}
+	source, ok := oauth2Sources.sources[key]
+	if !ok {
+		source = &tokenSource{opts: opts, client: &http.Client{Transport: rt, Timeout: 30 * time.Second}}
+		oauth2Sources.sources[key] = source
+	}
+	return source
+}
+
+// token returns a valid access token, fetching a new one when needed.
+// The fetch deliberately doesn't use the request's context, which
+// carries its httptrace hooks.
+func (s *tokenSource) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.access != "" && (s.expiry.IsZero() || time.Until(s.expiry) > tokenRefreshMargin) {
+		return s.access, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if s.opts.OAuth2Scopes != "" {
+		form.Set("scope", s.opts.OAuth2Scopes)
+	}
+	req, err := http.NewRequest("POST", s.opts.OAuth2TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.opts.OAuth2ClientID), url.QueryEscape(s.opts.OAuth2ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching OAuth2 token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("fetching OAuth2 token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching OAuth2 token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("decoding OAuth2 token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("OAuth2 token response has no access_token")
+	}
+	s.access = token.AccessToken
+	s.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return s.access, nil
+}
+
+// invalidate drops token if it's still the current one, forcing the
+// next call to fetch a new token.
+func (s *tokenSource) invalidate(token string) {
+	s.mu.Lock()
+	if s.access == token {
+		s.access = ""
+	}
+	s.mu.Unlock()
+}
+
+type oauth2Auth struct {
+	rt     http.RoundTripper
+	source *tokenSource
+}
+
+func (t *oauth2Auth) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.token()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.rt.RoundTrip(withHeader(req, "Authorization", "Bearer "+token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The server may have revoked the token early; fetch a new one and
+	// retry once.
+	retry, ok := rewind(req)
+	if !ok {
+		return resp, nil
+	}
+	t.source.invalidate(token)
+	token, err = t.source.token()
+	if err != nil {
+		return resp, nil
+	}
+	drainAndClose(resp.Body)
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return t.rt.RoundTrip(retry)
+}
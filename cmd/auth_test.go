@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	tests := []struct {
+		headers []string
+		want    map[string]string
+	}{
+		{
+			[]string{`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`},
+			map[string]string{"realm": "testrealm@host.com", "qop": "auth,auth-int", "nonce": "dcd98b7102dd2f0e8b11d0f600bfb0c093", "opaque": "5ccc069c403ebaf9f0171e9517f40e41"},
+		},
+		{
+			[]string{`Basic realm="x"`, `digest Realm="a, b", NONCE=abc, algorithm=SHA-256, stale=false`},
+			map[string]string{"realm": "a, b", "nonce": "abc", "algorithm": "SHA-256", "stale": "false"},
+		},
+		{[]string{`Basic realm="x"`}, nil},
+		{[]string{`Digest realm="no nonce"`}, nil},
+		{[]string{`Digest realm="unterminated`}, nil},
+	}
+	for _, tt := range tests {
+		got, _ := parseDigestChallenge(tt.headers)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigestAuth(t *testing.T) {
+	const realm, nonce = "testrealm@host.com", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	challenges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := parseDigestChallenge([]string{r.Header.Get("Authorization")})
+		if ok {
+			ha1 := md5Hex("Mufasa:" + realm + ":Circle Of Life")
+			ha2 := md5Hex(r.Method + ":" + c["uri"])
+			want := md5Hex(ha1 + ":" + nonce + ":" + c["nc"] + ":" + c["cnonce"] + ":auth:" + ha2)
+			if c["response"] == want && c["uri"] == r.URL.RequestURI() && c["opaque"] == "xyz" {
+				fmt.Fprint(w, "ok ", c["nc"])
+				return
+			}
+		}
+		challenges++
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", qop="auth", nonce="%s", opaque="xyz"`, realm, nonce))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	rt, err := authOptions{Digest: "Mufasa:Circle Of Life"}.wrap(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"ok 00000001", "ok 00000002"} {
+		req, _ := http.NewRequest("GET", server.URL+"/dir/index.html?a=1", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body[:n]) != want {
+			t.Errorf("request %d: got %s %q, want %q", i, resp.Status, body[:n], want)
+		}
+	}
+	// The second request authenticates with the stored challenge.
+	if challenges != 1 {
+		t.Errorf("server sent %d challenges, want 1", challenges)
+	}
+}
@@ -0,0 +1,203 @@
+// cmd/compression.go
+//
+// Content-Encoding is negotiated and decoded here instead of by the
+// protocol transports (which have compression disabled), so the body can
+// be counted both as it arrives on the wire and after decoding.
+
+package cmd
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.Compressed, "compressed", false, "Ask for gzip, deflate, br and zstd responses and decode them (default asks for gzip only)")
+}
+
+// bodySizes counts a response body as received and as decoded. The
+// counts grow while the body is read.
+type bodySizes struct {
+	Encoding string
+	wire     atomic.Int64
+	decoded  atomic.Int64
+}
+
+func (s *bodySizes) Wire() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.wire.Load()
+}
+
+func (s *bodySizes) Decoded() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.decoded.Load()
+}
+
+// bodySummary describes the response body's encoding and size, once it
+// has been read.
+func bodySummary(measured measuredResponse) string {
+	sizes := measured.Body
+	if sizes == nil {
+		return ""
+	}
+	if sizes.Encoding == "identity" {
+		return fmt.Sprintf("identity, %s", formatBytes(sizes.Wire()))
+	}
+	return fmt.Sprintf("%s, %s on the wire, %s decoded", sizes.Encoding, formatBytes(sizes.Wire()), formatBytes(sizes.Decoded()))
+}
+
+func withCompression(rt http.RoundTripper, compressed bool) http.RoundTripper {
+	acceptEncoding := "gzip"
+	if compressed {
+		acceptEncoding = "gzip, deflate, br, zstd"
+	}
+	return &compressionTransport{rt: rt, acceptEncoding: acceptEncoding}
+}
+
+type compressionTransport struct {
+	rt             http.RoundTripper
+	acceptEncoding string
+}
+
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Like the standard transport, leave range requests alone: the range
+	// would apply to the encoded body.
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != "HEAD" {
+		req = withHeader(req, "Accept-Encoding", t.acceptEncoding)
+	}
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := &bodySizes{Encoding: strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))}
+	if sizes.Encoding == "" {
+		sizes.Encoding = "identity"
+	}
+	if measured := measuredFromContext(req.Context()); measured != nil {
+		measured.Body = sizes
+	}
+
+	empty := req.Method == "HEAD" || resp.ContentLength == 0 ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified
+	if empty || !canDecode(sizes.Encoding) {
+		resp.Body = &countedBody{ReadCloser: resp.Body, sizes: sizes}
+		return resp, nil
+	}
+
+	resp.Body = &decodedBody{raw: resp.Body, sizes: sizes}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func canDecode(encoding string) bool {
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br", "zstd":
+		return true
+	}
+	return false
+}
+
+// countedBody is a body passed through as is, so wire and decoded sizes
+// are the same.
+type countedBody struct {
+	io.ReadCloser
+	sizes *bodySizes
+}
+
+func (b *countedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.sizes.wire.Add(int64(n))
+	b.sizes.decoded.Add(int64(n))
+	return n, err
+}
+
+type wireCounter struct {
+	r     io.Reader
+	sizes *bodySizes
+}
+
+func (c wireCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.sizes.wire.Add(int64(n))
+	return n, err
+}
+
+// decodedBody decodes on first read, so the decoder doesn't block the
+// round trip waiting for the first bytes of the body.
+type decodedBody struct {
+	raw     io.ReadCloser
+	sizes   *bodySizes
+	decoder io.Reader
+	close   func()
+	err     error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && b.err == nil {
+		b.decoder, b.close, b.err = newDecoder(b.sizes.Encoding, wireCounter{b.raw, b.sizes})
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.decoder.Read(p)
+	b.sizes.decoded.Add(int64(n))
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	if b.close != nil {
+		b.close()
+	}
+	return b.raw.Close()
+}
+
+func newDecoder(encoding string, r io.Reader) (io.Reader, func(), error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { zr.Close() }, nil
+	case "deflate":
+		// "deflate" should be zlib wrapped, but some servers send a raw
+		// deflate stream. A zlib header is recognisable, so accept both.
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, func() { zr.Close() }, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, func() { fr.Close() }, nil
+	case "br":
+		return brotli.NewReader(r), nil, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+}
@@ -0,0 +1,357 @@
+// cmd/config.go
+//
+// Config file with named profiles. The user file (~/.config/hpgo/config.yaml
+// on Linux) is read first, then .hpgo/config.yaml from the project, whose
+// profiles override the user's field by field:
+//
+//	default_profile: dev
+//	profiles:
+//	  staging:
+//	    base_url: https://staging.example.com
+//	    headers:
+//	      X-Env: staging
+//	    auth:
+//	      bearer: '{{secret "staging_token"}}'
+//	    tls:
+//	      cacert: certs/staging.pem
+//	    timeout: 10s
+//	    workers: 20
+//
+// A profile only fills in flags that weren't given on the command line,
+// and any auth or signing flag given there replaces the profile's auth
+// as a whole. Relative tls paths are resolved against the directory of the config
+// file they're in. Naming an auth scheme in an overriding profile
+// replaces the scheme of the one it overrides.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+type profileAuth struct {
+	Basic              string `yaml:"basic"`
+	Bearer             string `yaml:"bearer"`
+	Digest             string `yaml:"digest"`
+	OAuth2TokenURL     string `yaml:"oauth2_token_url"`
+	OAuth2ClientID     string `yaml:"oauth2_client_id"`
+	OAuth2ClientSecret string `yaml:"oauth2_client_secret"`
+	OAuth2Scopes       string `yaml:"oauth2_scopes"`
+}
+
+type profileTLS struct {
+	CACert     string `yaml:"cacert"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	Insecure   bool   `yaml:"insecure"`
+	ServerName string `yaml:"servername"`
+	MinVersion string `yaml:"min_version"`
+}
+
+type profile struct {
+	BaseURL string            `yaml:"base_url"`
+	Headers map[string]string `yaml:"headers"`
+	Auth    profileAuth       `yaml:"auth"`
+	TLS     profileTLS        `yaml:"tls"`
+	Timeout string            `yaml:"timeout"`
+	Workers int               `yaml:"workers"`
+}
+
+type configFile struct {
+	DefaultProfile string              `yaml:"default_profile"`
+	Profiles       map[string]*profile `yaml:"profiles"`
+}
+
+var profileFlag string
+
+// activeProfile is the profile in use, if any. Set before any command runs.
+var activeProfile *profile
+
+// profileErr is why the selected profile couldn't be applied. It's
+// reported once a command builds a transport or needs the base_url.
+var profileErr error
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Config profile to use (default is the config's default_profile)")
+}
+
+func configPaths() []string {
+	var paths []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(configDir, "hpgo", "config.yaml"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if dir, ok := findUp(cwd, ".hpgo"); ok {
+			paths = append(paths, filepath.Join(dir, "config.yaml"))
+		}
+	}
+	return paths
+}
+
+// loadConfig reads and merges the config files. Missing files are fine.
+func loadConfig() (configFile, error) {
+	merged := configFile{Profiles: make(map[string]*profile)}
+	for _, path := range configPaths() {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return merged, err
+		}
+		var file configFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return merged, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, p := range file.Profiles {
+			if p != nil {
+				p.TLS.resolve(filepath.Dir(path))
+			}
+		}
+		if file.DefaultProfile != "" {
+			merged.DefaultProfile = file.DefaultProfile
+		}
+		for name, p := range file.Profiles {
+			if p == nil {
+				continue
+			}
+			if base, ok := merged.Profiles[name]; ok {
+				base.merge(p)
+			} else {
+				merged.Profiles[name] = p
+			}
+		}
+	}
+	return merged, nil
+}
+
+// merge overlays the fields set in override.
+func (p *profile) merge(override *profile) {
+	if override.BaseURL != "" {
+		p.BaseURL = override.BaseURL
+	}
+	for name, value := range override.Headers {
+		if p.Headers == nil {
+			p.Headers = make(map[string]string)
+		}
+		p.Headers[name] = value
+	}
+	p.Auth.merge(override.Auth)
+	p.TLS.merge(override.TLS)
+	if override.Timeout != "" {
+		p.Timeout = override.Timeout
+	}
+	if override.Workers != 0 {
+		p.Workers = override.Workers
+	}
+}
+
+// overlay sets *field to value when value is set.
+func overlay(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+func (a *profileAuth) merge(override profileAuth) {
+	if override.Basic != "" || override.Bearer != "" || override.Digest != "" || override.OAuth2TokenURL != "" {
+		// The schemes are mutually exclusive.
+		a.Basic, a.Bearer, a.Digest, a.OAuth2TokenURL = "", "", "", ""
+	}
+	overlay(&a.Basic, override.Basic)
+	overlay(&a.Bearer, override.Bearer)
+	overlay(&a.Digest, override.Digest)
+	overlay(&a.OAuth2TokenURL, override.OAuth2TokenURL)
+	overlay(&a.OAuth2ClientID, override.OAuth2ClientID)
+	overlay(&a.OAuth2ClientSecret, override.OAuth2ClientSecret)
+	overlay(&a.OAuth2Scopes, override.OAuth2Scopes)
+}
+
+func (t *profileTLS) merge(override profileTLS) {
+	overlay(&t.CACert, override.CACert)
+	overlay(&t.Cert, override.Cert)
+	overlay(&t.Key, override.Key)
+	overlay(&t.ServerName, override.ServerName)
+	overlay(&t.MinVersion, override.MinVersion)
+	if override.Insecure {
+		t.Insecure = true
+	}
+}
+
+// resolve makes the relative file paths absolute against dir. Values
+// starting with ${...} or {{...}} are left for interpolation.
+func (t *profileTLS) resolve(dir string) {
+	for _, field := range []*string{&t.CACert, &t.Cert, &t.Key} {
+		path := *field
+		if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "${") || strings.HasPrefix(path, "{{") {
+			continue
+		}
+		*field = filepath.Join(dir, path)
+	}
+}
+
+// interpolate expands ${NAME} and {{secret "name"}} in the profile's
+// string values.
+func (p *profile) interpolate() error {
+	fields := []*string{
+		&p.BaseURL, &p.Timeout,
+		&p.Auth.Basic, &p.Auth.Bearer, &p.Auth.Digest,
+		&p.Auth.OAuth2TokenURL, &p.Auth.OAuth2ClientID, &p.Auth.OAuth2ClientSecret, &p.Auth.OAuth2Scopes,
+		&p.TLS.CACert, &p.TLS.Cert, &p.TLS.Key, &p.TLS.ServerName, &p.TLS.MinVersion,
+	}
+	for _, field := range fields {
+		expanded, err := interpolate(*field)
+		if err != nil {
+			return err
+		}
+		*field = expanded
+	}
+	for key, value := range p.Headers {
+		expanded, err := interpolate(value)
+		if err != nil {
+			return err
+		}
+		p.Headers[key] = expanded
+	}
+	return nil
+}
+
+// flagValues maps flag names to the profile's values for them.
+func (p *profile) flagValues() map[string]string {
+	values := map[string]string{
+		"basic":                p.Auth.Basic,
+		"bearer":               p.Auth.Bearer,
+		"digest":               p.Auth.Digest,
+		"oauth2-token-url":     p.Auth.OAuth2TokenURL,
+		"oauth2-client-id":     p.Auth.OAuth2ClientID,
+		"oauth2-client-secret": p.Auth.OAuth2ClientSecret,
+		"oauth2-scopes":        p.Auth.OAuth2Scopes,
+		"cacert":               p.TLS.CACert,
+		"cert":                 p.TLS.Cert,
+		"key":                  p.TLS.Key,
+		"servername":           p.TLS.ServerName,
+		"tls-min-version":      p.TLS.MinVersion,
+		"timeout":              p.Timeout,
+	}
+	if p.TLS.Insecure {
+		values["insecure"] = "true"
+	}
+	if p.Workers > 0 {
+		values["workers"] = strconv.Itoa(p.Workers)
+	}
+	return values
+}
+
+// profileAuthFlags are the flags a profile's auth section sets.
+var profileAuthFlags = []string{
+	"basic", "bearer", "digest",
+	"oauth2-token-url", "oauth2-client-id", "oauth2-client-secret", "oauth2-scopes",
+}
+
+// credentialFlagGiven reports whether an auth or signing flag was set on
+// the command line. Mixing them with the profile's auth would only trip
+// the checks that schemes are mutually exclusive.
+func credentialFlagGiven(cmd *cobra.Command) bool {
+	signing := []string{
+		"aws-sigv4", "aws-access-key", "aws-secret-key", "aws-session-token",
+		"hmac-key", "hmac-header", "hmac-algorithm", "hmac-timestamp-header",
+	}
+	for _, flagName := range append(signing, profileAuthFlags...) {
+		if flag := cmd.Flags().Lookup(flagName); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// applyProfile loads the selected profile and fills in the flags of cmd
+// that weren't set on the command line.
+func applyProfile(cmd *cobra.Command) error {
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	name := profileFlag
+	if name == "" {
+		name = config.DefaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for n := range config.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (have: %s)", name, strings.Join(names, ", "))
+	}
+
+	// Commands that don't send requests, like 'secret set' storing the
+	// secret the profile needs, still run without it.
+	if err := p.interpolate(); err != nil {
+		profileErr = fmt.Errorf("profile %s: %w", name, err)
+		return nil
+	}
+
+	values := p.flagValues()
+	if credentialFlagGiven(cmd) {
+		for _, flagName := range profileAuthFlags {
+			delete(values, flagName)
+		}
+	}
+	for flagName, value := range values {
+		if value == "" {
+			continue
+		}
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("profile %s: invalid %s %q: %w", name, flagName, value, err)
+		}
+	}
+
+	// Headers from --header win over the profile's.
+	given := make(map[string]bool)
+	for _, header := range transportFlags.Headers {
+		key, _, _ := strings.Cut(header, ":")
+		given[http.CanonicalHeaderKey(strings.TrimSpace(key))] = true
+	}
+	for key, value := range p.Headers {
+		if !given[http.CanonicalHeaderKey(key)] {
+			transportFlags.Headers = append(transportFlags.Headers, key+": "+value)
+		}
+	}
+	activeProfile = p
+	return nil
+}
+
+// normalizeURL resolves URLs starting with "/" against the profile's
+// base_url and defaults everything else without a scheme to http://.
+func normalizeURL(raw string) (string, error) {
+	if strings.HasPrefix(raw, "/") {
+		if profileErr != nil {
+			return "", profileErr
+		}
+		if activeProfile == nil || activeProfile.BaseURL == "" {
+			return "", fmt.Errorf("relative URL %q needs a profile with a base_url", raw)
+		}
+		return strings.TrimSuffix(activeProfile.BaseURL, "/") + raw, nil
+	}
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		return "http://" + raw, nil
+	}
+	return raw, nil
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoadConfigMerge(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	os.MkdirAll(filepath.Join(home, "hpgo"), 0755)
+	os.MkdirAll(filepath.Join(project, ".hpgo"), 0755)
+
+	os.WriteFile(filepath.Join(home, "hpgo", "config.yaml"), []byte(`
+default_profile: dev
+profiles:
+  staging:
+    base_url: https://staging.example.com
+    headers:
+      X-Env: staging
+    auth:
+      oauth2_token_url: https://auth.example.com/token
+      oauth2_client_id: user-client
+      oauth2_client_secret: user-secret
+    tls:
+      cacert: certs/staging.pem
+      cert: /etc/hpgo/client.pem
+      servername: staging.internal
+    timeout: 10s
+  basic:
+    auth:
+      basic: a:b
+`), 0644)
+	os.WriteFile(filepath.Join(project, ".hpgo", "config.yaml"), []byte(`
+profiles:
+  staging:
+    headers:
+      X-Team: web
+    auth:
+      oauth2_client_secret: project-secret
+    tls:
+      key: ${HPGO_KEY_FILE}
+      insecure: true
+    workers: 20
+  basic:
+    auth:
+      bearer: token
+`), 0644)
+
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DefaultProfile != "dev" {
+		t.Errorf("default profile %q", config.DefaultProfile)
+	}
+
+	staging := config.Profiles["staging"]
+	want := profile{
+		BaseURL: "https://staging.example.com",
+		Headers: map[string]string{"X-Env": "staging", "X-Team": "web"},
+		Auth: profileAuth{
+			OAuth2TokenURL:     "https://auth.example.com/token",
+			OAuth2ClientID:     "user-client",
+			OAuth2ClientSecret: "project-secret",
+		},
+		TLS: profileTLS{
+			CACert:     filepath.Join(home, "hpgo", "certs", "staging.pem"),
+			Cert:       "/etc/hpgo/client.pem",
+			Key:        "${HPGO_KEY_FILE}",
+			Insecure:   true,
+			ServerName: "staging.internal",
+		},
+		Timeout: "10s",
+		Workers: 20,
+	}
+	if staging.BaseURL != want.BaseURL || staging.Auth != want.Auth || staging.TLS != want.TLS ||
+		staging.Timeout != want.Timeout || staging.Workers != want.Workers || len(staging.Headers) != 2 ||
+		staging.Headers["X-Env"] != "staging" || staging.Headers["X-Team"] != "web" {
+		t.Errorf("staging:\n got %+v\nwant %+v", *staging, want)
+	}
+
+	// A different scheme replaces the user's rather than clashing with it.
+	if got := config.Profiles["basic"].Auth; got != (profileAuth{Bearer: "token"}) {
+		t.Errorf("basic auth: got %+v", got)
+	}
+}
+
+// A profile whose secret isn't stored yet mustn't keep 'secret set' from
+// storing it; only commands sending requests need the profile.
+func TestProfileSecretNotStoredYet(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HPGO_SECRETS_FILE", filepath.Join(home, "secrets.enc"))
+	t.Setenv("HPGO_SECRET_KEY", "test passphrase")
+	os.MkdirAll(filepath.Join(home, "hpgo"), 0755)
+	os.WriteFile(filepath.Join(home, "hpgo", "config.yaml"), []byte(`
+default_profile: staging
+profiles:
+  staging:
+    base_url: https://staging.example.com
+    auth:
+      bearer: '{{secret "staging_token"}}'
+`), 0644)
+
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(t.TempDir())
+	saved := transportFlags
+	defer func() {
+		transportFlags, activeProfile, profileErr, openedStore = saved, nil, nil, nil
+	}()
+
+	if err := applyProfile(secretSetCmd); err != nil {
+		t.Fatalf("secret set: %v", err)
+	}
+	if _, err := newBaseTransport(transportFlags); err == nil || !strings.Contains(err.Error(), `profile staging: secret "staging_token" not found`) {
+		t.Errorf("transport: got %v", err)
+	}
+	if _, err := normalizeURL("/users"); err == nil || !strings.Contains(err.Error(), "profile staging") {
+		t.Errorf("relative URL: got %v", err)
+	}
+
+	secretSetCmd.Run(secretSetCmd, []string{"staging_token", "abc"})
+	profileErr, openedStore = nil, nil
+	getCmd.InheritedFlags() // merges --bearer in, as parsing the command line would
+	if err := applyProfile(getCmd); err != nil || profileErr != nil {
+		t.Fatalf("get: %v, %v", err, profileErr)
+	}
+	if transportFlags.Auth.Bearer != "abc" {
+		t.Errorf("bearer %q", transportFlags.Auth.Bearer)
+	}
+	if url, err := normalizeURL("/users"); err != nil || url != "https://staging.example.com/users" {
+		t.Errorf("relative URL: got %s, %v", url, err)
+	}
+}
+
+func TestProfileAuthGivesWayToFlags(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	os.MkdirAll(filepath.Join(home, "hpgo"), 0755)
+	os.WriteFile(filepath.Join(home, "hpgo", "config.yaml"), []byte(`
+default_profile: dev
+profiles:
+  dev:
+    auth:
+      bearer: profile-token
+    timeout: 3s
+`), 0644)
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(t.TempDir())
+
+	saved := transportFlags
+	reset := func() {
+		transportFlags, activeProfile, profileErr = saved, nil, nil
+		rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) { flag.Changed = false })
+	}
+	defer reset()
+
+	tests := []struct {
+		flags  []string
+		bearer string
+		basic  string
+	}{
+		{nil, "profile-token", ""},
+		{[]string{"--timeout", "5s"}, "profile-token", ""},
+		{[]string{"--basic", "u:p"}, "", "u:p"},
+		{[]string{"--aws-sigv4", "us-east-1:service", "--aws-access-key", "AKID", "--aws-secret-key", "secret"}, "", ""},
+		{[]string{"--hmac-key", "k"}, "", ""},
+	}
+	for _, tt := range tests {
+		reset()
+		if err := rootCmd.ParseFlags(tt.flags); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyProfile(rootCmd); err != nil {
+			t.Fatalf("%v: %v", tt.flags, err)
+		}
+		if transportFlags.Auth.Bearer != tt.bearer || transportFlags.Auth.Basic != tt.basic {
+			t.Errorf("%v: bearer %q basic %q, want %q %q", tt.flags, transportFlags.Auth.Bearer, transportFlags.Auth.Basic, tt.bearer, tt.basic)
+		}
+		if _, err := newTransport(transportFlags); err != nil {
+			t.Errorf("%v: %v", tt.flags, err)
+		}
+	}
+}
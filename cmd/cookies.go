@@ -0,0 +1,369 @@
+// cmd/cookies.go
+//
+// Cookie jars. Each command run gets one jar, and each execute line and
+// stress worker its own, so scenarios and virtual users don't share
+// sessions. Jars can be seeded from and
+// saved to a Netscape format cookie file (the one curl and browsers'
+// export tools use); what the jars changed is merged back into it.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+type cookieOptions struct {
+	Enabled bool
+	Values  []string
+	JarFile string
+	Show    bool
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.Cookies.Enabled, "cookies", false, "Keep cookies between requests")
+	rootCmd.PersistentFlags().StringArrayVar(&transportFlags.Cookies.Values, "cookie", nil, "NAME=VALUE cookie sent with every request (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.Cookies.JarFile, "cookie-jar", "", "Netscape cookie file to load before and save after the run")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.Cookies.Show, "show-cookies", false, "Print every Set-Cookie header received")
+}
+
+func (opts cookieOptions) enabled() bool {
+	return opts.Enabled || opts.JarFile != "" || len(opts.Values) > 0
+}
+
+// cookieEntry is a cookie as stored in a Netscape cookie file.
+type cookieEntry struct {
+	domain            string
+	includeSubdomains bool
+	path              string
+	secure            bool
+	httpOnly          bool
+	expires           time.Time
+	name, value       string
+}
+
+// cookieChange is a cookie a response set or deleted. seq orders the
+// changes of all jars, so the latest one wins when they're merged.
+type cookieChange struct {
+	entry   cookieEntry
+	deleted bool
+	seq     uint64
+}
+
+var cookieSeq atomic.Uint64
+
+type cookieJar struct {
+	jar    *cookiejar.Jar
+	static []*http.Cookie
+	show   bool
+
+	mu      sync.Mutex
+	changes map[string]cookieChange
+}
+
+// openJars are saved to --cookie-jar once the command finishes: the
+// file as loaded, with the changes of every jar applied.
+var openJars struct {
+	sync.Mutex
+	list   []*cookieJar
+	file   string
+	loaded []cookieEntry
+}
+
+func (opts cookieOptions) newJar() (*cookieJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	cj := &cookieJar{jar: jar, show: opts.Show, changes: make(map[string]cookieChange)}
+
+	for _, raw := range opts.Values {
+		name, value, ok := strings.Cut(raw, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --cookie %q, expected NAME=VALUE", raw)
+		}
+		cj.static = append(cj.static, &http.Cookie{Name: name, Value: value})
+	}
+
+	if opts.JarFile != "" {
+		loaded, err := cj.load(opts.JarFile)
+		if err != nil {
+			return nil, err
+		}
+		openJars.Lock()
+		if len(openJars.list) == 0 {
+			openJars.loaded = loaded
+		}
+		openJars.list = append(openJars.list, cj)
+		openJars.file = opts.JarFile
+		openJars.Unlock()
+	}
+	return cj, nil
+}
+
+// load puts the cookies of file into the jar and returns them.
+func (cj *cookieJar) load(file string) ([]cookieEntry, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cookie jar: %w", err)
+	}
+	defer f.Close()
+
+	var loaded []cookieEntry
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab separated fields", file, lineNumber)
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry %q", file, lineNumber, fields[4])
+		}
+		entry := cookieEntry{
+			domain:            fields[0],
+			includeSubdomains: fields[1] == "TRUE",
+			path:              fields[2],
+			secure:            fields[3] == "TRUE",
+			httpOnly:          httpOnly,
+			name:              fields[5],
+			value:             fields[6],
+		}
+		if expiry > 0 {
+			entry.expires = time.Unix(expiry, 0)
+		}
+		cj.set(entry)
+		loaded = append(loaded, entry)
+	}
+	return loaded, scanner.Err()
+}
+
+// key identifies a cookie the way the jar does. A host-only cookie and
+// a domain cookie of the same name are different cookies.
+func (e cookieEntry) key() string {
+	return strings.Join([]string{strings.TrimPrefix(e.domain, "."), e.path, e.name, boolField(e.includeSubdomains)}, "\x00")
+}
+
+// set stores entry in the jar.
+func (cj *cookieJar) set(entry cookieEntry) {
+	host := strings.TrimPrefix(entry.domain, ".")
+	scheme := "http"
+	if entry.secure {
+		scheme = "https"
+	}
+	cookie := &http.Cookie{
+		Name:     entry.name,
+		Value:    entry.value,
+		Path:     entry.path,
+		Secure:   entry.secure,
+		HttpOnly: entry.httpOnly,
+		Expires:  entry.expires,
+	}
+	if entry.includeSubdomains {
+		cookie.Domain = host
+	}
+	cj.jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: entry.path}, []*http.Cookie{cookie})
+}
+
+// defaultCookiePath is the directory of the request path (RFC 6265
+// section 5.1.4), used when Set-Cookie has no Path.
+func defaultCookiePath(u *url.URL) string {
+	if u.Path == "" || !strings.HasPrefix(u.Path, "/") || strings.Count(u.Path, "/") == 1 {
+		return "/"
+	}
+	return path.Dir(u.Path)
+}
+
+// stored reports whether the jar kept entry; it drops cookies for other
+// domains and public suffixes.
+func (cj *cookieJar) stored(entry cookieEntry) bool {
+	scheme := "http"
+	if entry.secure {
+		scheme = "https"
+	}
+	u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(entry.domain, "."), Path: entry.path}
+	for _, c := range cj.jar.Cookies(u) {
+		if c.Name == entry.name && c.Value == entry.value {
+			return true
+		}
+	}
+	return false
+}
+
+// received records cookies set or deleted by a response to u. Only
+// changes the jar accepted are kept for the cookie file.
+func (cj *cookieJar) received(u *url.URL, cookies []*http.Cookie) {
+	cj.jar.SetCookies(u, cookies)
+
+	cj.mu.Lock()
+	defer cj.mu.Unlock()
+	for _, c := range cookies {
+		entry := cookieEntry{
+			domain:   u.Hostname(),
+			path:     c.Path,
+			secure:   c.Secure,
+			httpOnly: c.HttpOnly,
+			expires:  c.Expires,
+			name:     c.Name,
+			value:    c.Value,
+		}
+		if c.Domain != "" {
+			entry.domain = "." + strings.TrimPrefix(c.Domain, ".")
+			entry.includeSubdomains = true
+		}
+		if entry.path == "" {
+			entry.path = defaultCookiePath(u)
+		}
+		if c.MaxAge > 0 {
+			entry.expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || (!entry.expires.IsZero() && entry.expires.Before(time.Now())) {
+			if domainMatch(u.Hostname(), entry.domain) {
+				cj.changes[entry.key()] = cookieChange{entry: entry, deleted: true, seq: cookieSeq.Add(1)}
+			}
+			continue
+		}
+		if !cj.stored(entry) {
+			continue
+		}
+		cj.changes[entry.key()] = cookieChange{entry: entry, seq: cookieSeq.Add(1)}
+	}
+}
+
+// domainMatch reports whether a response from host may set cookies for
+// domain, which mustn't be a public suffix.
+func domainMatch(host, domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// wrap returns rt sending and collecting cookies through cj. A nil jar
+// returns rt unchanged.
+func (cj *cookieJar) wrap(rt http.RoundTripper) http.RoundTripper {
+	if cj == nil {
+		return rt
+	}
+	return &cookieTransport{rt: rt, jar: cj}
+}
+
+type cookieTransport struct {
+	rt  http.RoundTripper
+	jar *cookieJar
+}
+
+func (t *cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	for _, c := range t.jar.jar.Cookies(req.URL) {
+		clone.AddCookie(c)
+	}
+	if sameOrigin(req) {
+		for _, c := range t.jar.static {
+			clone.AddCookie(c)
+		}
+	}
+
+	resp, err := t.rt.RoundTrip(clone)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		t.jar.received(req.URL, cookies)
+		if t.jar.show {
+			for _, raw := range resp.Header.Values("Set-Cookie") {
+				fmt.Printf("Set-Cookie from %s: %s\n", req.URL.Host, raw)
+			}
+		}
+	}
+	return resp, nil
+}
+
+func boolField(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// saveCookieJars writes every jar opened with --cookie-jar back to the
+// file: the cookies loaded from it, with what each jar (one per execute
+// line or stress worker) set or deleted applied in the order it
+// happened.
+func saveCookieJars() {
+	openJars.Lock()
+	jars, file, loaded := openJars.list, openJars.file, openJars.loaded
+	openJars.list, openJars.loaded = nil, nil
+	openJars.Unlock()
+	if len(jars) == 0 {
+		return
+	}
+
+	merged := make(map[string]cookieEntry)
+	for _, entry := range loaded {
+		merged[entry.key()] = entry
+	}
+	var changes []cookieChange
+	for _, cj := range jars {
+		cj.mu.Lock()
+		for _, change := range cj.changes {
+			changes = append(changes, change)
+		}
+		cj.mu.Unlock()
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].seq < changes[j].seq })
+	for _, change := range changes {
+		if change.deleted {
+			delete(merged, change.entry.key())
+		} else {
+			merged[change.entry.key()] = change.entry
+		}
+	}
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, key := range keys {
+		entry := merged[key]
+		domain := entry.domain
+		if entry.httpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		var expiry int64
+		if !entry.expires.IsZero() {
+			expiry = entry.expires.Unix()
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, boolField(entry.includeSubdomains),
+			entry.path, boolField(entry.secure), expiry, entry.name, entry.value)
+	}
+	if err := os.WriteFile(file, []byte(b.String()), 0600); err != nil {
+		fmt.Println("Error saving cookie jar:", err)
+	}
+}
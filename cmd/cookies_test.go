@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieJarFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cookies.txt")
+	future := time.Now().Add(time.Hour).Unix()
+	content := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".example.com\tTRUE\t/\tFALSE\t0\tsession\tabc",
+		"#HttpOnly_api.example.com\tFALSE\t/v1\tTRUE\t" + strconv.FormatInt(future, 10) + "\ttoken\txyz",
+	}, "\n") + "\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cj, err := cookieOptions{JarFile: file}.newJar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://www.example.com/", "session=abc"},
+		{"https://api.example.com/v1/users", "token=xyz session=abc"},
+		{"http://api.example.com/v1/users", "session=abc"},
+		{"https://api.example.com/v2", "session=abc"},
+		{"https://other.com/", ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		var got []string
+		for _, c := range cj.jar.Cookies(u) {
+			got = append(got, c.Name+"="+c.Value)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	u, _ := url.Parse("https://api.example.com/v1/login")
+	cj.received(u, []*http.Cookie{
+		{Name: "fresh", Value: "1", Path: "/"},
+		{Name: "foreign", Value: "1", Domain: "evil.com"},
+		{Name: "suffix", Value: "1", Domain: "com"},
+		{Name: "session", Value: "", MaxAge: -1, Domain: "example.com", Path: "/"},
+	})
+	saveCookieJars()
+
+	saved, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"api.example.com\tFALSE\t/\tFALSE\t0\tfresh\t1",
+		"#HttpOnly_api.example.com\tFALSE\t/v1\tTRUE\t" + strconv.FormatInt(future, 10) + "\ttoken\txyz",
+	}, "\n") + "\n"
+	if string(saved) != want {
+		t.Errorf("saved jar:\n%s\nwant:\n%s", saved, want)
+	}
+}
+
+// Stress workers load the file into jars of their own; saving merges
+// what each changed without bringing back cookies another deleted.
+func TestCookieJarMerge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cookies.txt")
+	content := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"example.com\tFALSE\t/\tFALSE\t0\tid\thost-only",
+		".example.com\tTRUE\t/\tFALSE\t0\tid\tdomain",
+		"example.com\tFALSE\t/\tFALSE\t0\tkeep\t1",
+	}, "\n") + "\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var jars []*cookieJar
+	for i := 0; i < 3; i++ {
+		cj, err := cookieOptions{JarFile: file}.newJar()
+		if err != nil {
+			t.Fatal(err)
+		}
+		jars = append(jars, cj)
+	}
+	u, _ := url.Parse("http://example.com/")
+	jars[0].received(u, []*http.Cookie{{Name: "id", MaxAge: -1, Path: "/"}})
+	jars[1].received(u, []*http.Cookie{{Name: "new", Value: "1", Path: "/"}})
+	jars[2].received(u, []*http.Cookie{{Name: "new", Value: "2", Path: "/"}})
+	jars[0].received(u, []*http.Cookie{{Name: "keep", MaxAge: -1, Path: "/"}})
+	jars[1].received(u, []*http.Cookie{{Name: "keep", Value: "again", Path: "/"}})
+	jars[1].received(&url.URL{Scheme: "http", Host: "evil.com", Path: "/"}, []*http.Cookie{{Name: "id", MaxAge: -1, Domain: "example.com", Path: "/"}})
+	saveCookieJars()
+
+	saved, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		".example.com\tTRUE\t/\tFALSE\t0\tid\tdomain",
+		"example.com\tFALSE\t/\tFALSE\t0\tkeep\tagain",
+		"example.com\tFALSE\t/\tFALSE\t0\tnew\t2",
+	}, "\n") + "\n"
+	if string(saved) != want {
+		t.Errorf("saved jar:\n%s\nwant:\n%s", saved, want)
+	}
+}
+
+func TestInvalidCookieJarFile(t *testing.T) {
+	for _, content := range []string{"example.com\tFALSE\t/\n", "example.com\tFALSE\t/\tFALSE\tsoon\tname\tvalue\n"} {
+		file := filepath.Join(t.TempDir(), "cookies.txt")
+		os.WriteFile(file, []byte(content), 0600)
+		if _, err := (cookieOptions{JarFile: file}).newJar(); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+	openJars.Lock()
+	openJars.list = nil
+	openJars.Unlock()
+}
+
+// Stress workers are separate virtual users, so each gets its own jar.
+func TestSessionsHaveOwnJars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("id"); err == nil {
+			w.Write([]byte(c.Value))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "id", Value: r.URL.Query().Get("id")})
+	}))
+	defer server.Close()
+
+	cfg := transportConfig{Cookies: cookieOptions{Enabled: true}}
+	shared := http.DefaultTransport
+	var sessions []http.RoundTripper
+	for i := 0; i < 2; i++ {
+		session, err := cfg.sessionTransport(shared)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, session)
+	}
+	get := func(rt http.RoundTripper, query string) string {
+		req, _ := http.NewRequest("GET", server.URL+"/?"+query, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body := make([]byte, 16)
+		n, _ := resp.Body.Read(body)
+		return string(body[:n])
+	}
+	get(sessions[0], "id=a")
+	get(sessions[1], "id=b")
+	if got := get(sessions[0], ""); got != "a" {
+		t.Errorf("first session sent %q, want a", got)
+	}
+	if got := get(sessions[1], ""); got != "b" {
+		t.Errorf("second session sent %q, want b", got)
+	}
+}
@@ -1,5 +1,5 @@
 /*
-'create' creates new .txt files inside your executable folder.
+'create' creates new .txt files inside your workspace folder.
 
 Usage:
 
@@ -20,22 +20,26 @@ import (
 
 var createCmd = &cobra.Command{
 	Use:   "create [fileName]",
-	Short: "Creates txt file in current directory",
+	Short: "Creates txt file in the workspace",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		fileName := args[0]
-		
-		err := os.MkdirAll("executable", os.ModePerm)
-		if err != nil {
-			fmt.Println("Error creating directory:", err)
-			return
-		}
 	
 		if !strings.HasSuffix(fileName, ".txt") {
 			fileName = fileName + ".txt"
 		}
 
-		filePath := filepath.Join("executable", fileName)
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+		if err != nil {
+			fmt.Println("Error creating directory:", err)
+			return
+		}
 
 		if _, err := os.Stat(filePath); err == nil {
 			fmt.Printf("File '%s' already exists. Do you want to replace it? (y/n): ", fileName)
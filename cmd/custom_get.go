@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"io"
-	"strings"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +33,7 @@ func init() {
 	customGetCmd.Flags().StringVarP(&customGetFlags.CorrelationID, "correlation-id", "c", "", "Correlation ID")
 	customGetCmd.Flags().StringVarP(&customGetFlags.CustomHeader, "custom-header", "d", "", "Custom header")
 
+	addRenderFlags(customGetCmd)
 	rootCmd.AddCommand(customGetCmd)
 }
 
@@ -43,11 +43,12 @@ var customGetCmd = &cobra.Command{
 	Short: "Send a GET request to a URL (customizable)",
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		url := args[0]
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-            url = "http://" + url
-        }
+		url, err := normalizeURL(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
@@ -58,7 +59,7 @@ var customGetCmd = &cobra.Command{
 		headers := map[string]string{
 			"Accept":            customGetFlags.Format,
 			"User-Agent":        customGetFlags.UserAgent,
-			"Authorization":     customGetFlags.AuthToken,
+			"Authorization":     authorizationHeader(customGetFlags.AuthToken),
 			"X-Client-Version":  customGetFlags.ClientVersion,
 			"X-Api-Key":         customGetFlags.ApiKey,
 			"X-Correlation-ID":  customGetFlags.CorrelationID,
@@ -72,19 +73,33 @@ var customGetCmd = &cobra.Command{
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		if err := validateRenderFlags(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		schema, err := commandSchema()
+		if err != nil {
+			fmt.Println("Error reading schema:", err)
+			return
+		}
+
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
+		}
+		measured, err := measureRequest(client.Do, req)
 		if err != nil {
 			panic(err)
 		}
+		resp := measured.Res
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println("\nResponse status:\n", resp.Status)
-		fmt.Println("\nResponse header:\n", resp.Header)
-		fmt.Println("\nResponse body:\n", string(body))
+		printResponse(measured, body)
+		reportSchema(schema, body)
 	},
 }
@@ -31,6 +31,7 @@ func init() {
 	customPostCmd.Flags().StringVarP(&customPostFlags.CorrelationID, "correlation-id", "c", "", "Correlation ID")
 	customPostCmd.Flags().StringVarP(&customPostFlags.CustomHeader, "custom-header", "d", "", "Custom header")
 
+	addRenderFlags(customPostCmd)
 	rootCmd.AddCommand(customPostCmd)
 }
 
@@ -39,7 +40,11 @@ var customPostCmd = &cobra.Command{
 	Short: "Send a POST requests to a URL (customizable)",
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		url := args[0]
+		url, err := normalizeURL(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
 		req, err := http.NewRequest("Post", url, nil)
 		if err != nil {
@@ -50,7 +55,7 @@ var customPostCmd = &cobra.Command{
 		headers := map[string]string{
 			"Accept":            customPostFlags.Format,
 			"User-Agent":        customPostFlags.UserAgent,
-			"Authorization":     "Bearer " + customPostFlags.AuthToken,
+			"Authorization":     authorizationHeader(customPostFlags.AuthToken),
 			"X-Client-Version":  customPostFlags.ClientVersion,
 			"X-Api-Key":         customPostFlags.ApiKey,
 			"X-Correlation-ID":  customPostFlags.CorrelationID,
@@ -65,18 +70,27 @@ var customPostCmd = &cobra.Command{
 			}
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		if err := validateRenderFlags(); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
+		}
+		measured, err := measureRequest(client.Do, req)
 		if err != nil {
 			panic(err)
 		}
+		resp := measured.Res
 		defer resp.Body.Close()
 
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println("Response status:", resp.Status)
-		fmt.Println("Response body:", string(body))
+		printResponse(measured, body)
 	},
 }
@@ -0,0 +1,63 @@
+// cmd/delete_line.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteLineCmd = &cobra.Command{
+	Use:   "delete-line [fileName] [lineNumber]",
+	Short: "Deletes a line from a txt file, numbered as in 'show'",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName := args[0]
+		if !strings.HasSuffix(fileName, ".txt") {
+			fileName = fileName + ".txt"
+		}
+		lineNumber, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Println("Error converting lineNumber to integer:", err)
+			return
+		}
+
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fileLines, err := readFileLines(filePath)
+		if os.IsNotExist(err) {
+			fmt.Println("File does not exist:", fileName)
+			return
+		}
+		if err != nil {
+			fmt.Println("Error reading file:", err)
+			return
+		}
+		if lineNumber < 1 || lineNumber > len(fileLines) {
+			fmt.Printf("Line %d out of range, %s has %d lines\n", lineNumber, fileName, len(fileLines))
+			return
+		}
+
+		removed := fileLines[lineNumber-1]
+		fileLines = append(fileLines[:lineNumber-1], fileLines[lineNumber:]...)
+		content := strings.Join(fileLines, "\n")
+		if len(fileLines) > 0 {
+			content += "\n"
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			fmt.Println("Error writing file:", err)
+			return
+		}
+		fmt.Printf("Deleted line %d: %s\n", lineNumber, removed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteLineCmd)
+}
@@ -0,0 +1,127 @@
+// cmd/dial.go
+//
+// Where connections actually go. By default that's the URL's host, but
+// --unix-socket sends everything to a socket and --connect-to pins a
+// host:port to another backend while the URL (and so the Host header
+// and SNI) stays the same.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&transportFlags.UnixSocket, "unix-socket", "", "Connect through this Unix domain socket instead of TCP")
+	rootCmd.PersistentFlags().StringArrayVar(&transportFlags.ConnectTo, "connect-to", nil, "HOST:PORT:TARGET:PORT, connect to TARGET:PORT for requests to HOST:PORT (repeatable, empty HOST or PORT matches any)")
+}
+
+type connectToRule struct {
+	host, port             string
+	targetHost, targetPort string
+}
+
+// splitHostPortField cuts a leading "host:" or "[v6]:" off s.
+func splitHostPortField(s string) (field, rest string, ok bool) {
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]:")
+		if end < 0 {
+			return "", "", false
+		}
+		return s[1:end], s[end+2:], true
+	}
+	return strings.Cut(s, ":")
+}
+
+func parseConnectTo(raw string) (connectToRule, error) {
+	var rule connectToRule
+	rest := raw
+	var ok bool
+	if rule.host, rest, ok = splitHostPortField(rest); !ok {
+		return rule, fmt.Errorf("invalid --connect-to %q, expected HOST:PORT:TARGET:PORT", raw)
+	}
+	if rule.port, rest, ok = strings.Cut(rest, ":"); !ok {
+		return rule, fmt.Errorf("invalid --connect-to %q, expected HOST:PORT:TARGET:PORT", raw)
+	}
+	if rule.targetHost, rest, ok = splitHostPortField(rest); !ok {
+		return rule, fmt.Errorf("invalid --connect-to %q, expected HOST:PORT:TARGET:PORT", raw)
+	}
+	rule.targetPort = rest
+	if strings.Contains(rule.targetPort, ":") {
+		return rule, fmt.Errorf("invalid --connect-to %q, expected HOST:PORT:TARGET:PORT", raw)
+	}
+	return rule, nil
+}
+
+// apply rewrites addr if the rule matches it.
+func (rule connectToRule) apply(addr string) (string, bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, false
+	}
+	if (rule.host != "" && !strings.EqualFold(rule.host, host)) || (rule.port != "" && rule.port != port) {
+		return addr, false
+	}
+	if rule.targetHost != "" {
+		host = rule.targetHost
+	}
+	if rule.targetPort != "" {
+		port = rule.targetPort
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// addrRewriter maps a dial address through the --connect-to rules; the
+// first matching rule wins.
+type addrRewriter func(addr string) string
+
+func (cfg transportConfig) connectTarget() (addrRewriter, error) {
+	rules := make([]connectToRule, 0, len(cfg.ConnectTo))
+	for _, raw := range cfg.ConnectTo {
+		rule, err := parseConnectTo(raw)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return func(addr string) string {
+		for _, rule := range rules {
+			if rewritten, ok := rule.apply(addr); ok {
+				return rewritten
+			}
+		}
+		return addr
+	}, nil
+}
+
+// dialer returns the dial function for TCP based transports.
+func (cfg transportConfig) dialer() (dialFunc, error) {
+	base := newDialer()
+	if cfg.UnixSocket != "" {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return base.DialContext(ctx, "unix", cfg.UnixSocket)
+		}, nil
+	}
+
+	rewrite, err := cfg.connectTarget()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.resolvesItself() {
+		// Leave resolution to the dialer, which races IPv4 and IPv6
+		// (Happy Eyeballs) instead of trying addresses one by one.
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return base.DialContext(ctx, network, rewrite(addr))
+		}, nil
+	}
+	resolver, err := cfg.hostResolver()
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return resolver.dial(ctx, base, network, rewrite(addr))
+	}, nil
+}
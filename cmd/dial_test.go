@@ -0,0 +1,53 @@
+package cmd
+
+import "testing"
+
+func TestParseConnectTo(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want connectToRule
+	}{
+		{"example.com:443:10.0.0.1:8443", connectToRule{"example.com", "443", "10.0.0.1", "8443"}},
+		{"::backend:", connectToRule{"", "", "backend", ""}},
+		{"[::1]:80:[2001:db8::1]:8080", connectToRule{"::1", "80", "2001:db8::1", "8080"}},
+		{"example.com:443::", connectToRule{"example.com", "443", "", ""}},
+	}
+	for _, tt := range tests {
+		got, err := parseConnectTo(tt.raw)
+		if err != nil {
+			t.Errorf("%q: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+
+	for _, raw := range []string{"", "example.com", "example.com:443", "example.com:443:backend", "a:1:b:2:3", "[::1:80:b:1"} {
+		if _, err := parseConnectTo(raw); err == nil {
+			t.Errorf("%q: expected an error", raw)
+		}
+	}
+}
+
+func TestConnectToApply(t *testing.T) {
+	rewrite, err := transportConfig{ConnectTo: []string{
+		"Example.com:443:10.0.0.1:8443",
+		":80:proxy.internal:",
+	}}.connectTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ addr, want string }{
+		{"example.com:443", "10.0.0.1:8443"},
+		{"example.com:80", "proxy.internal:80"},
+		{"other.com:80", "proxy.internal:80"},
+		{"other.com:443", "other.com:443"},
+		{"[::1]:80", "proxy.internal:80"},
+	}
+	for _, tt := range tests {
+		if got := rewrite(tt.addr); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.addr, got, tt.want)
+		}
+	}
+}
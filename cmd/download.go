@@ -0,0 +1,344 @@
+// cmd/download.go
+//
+// Saving get responses to disk: -o names the file, --output-dir names it
+// after the URL. A progress line on stderr covers all concurrent
+// downloads, partial files can be resumed with a Range request, and the
+// result can be checked against a known checksum.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var downloadFlags struct {
+	Output    string
+	OutputDir string
+	Resume    bool
+	Checksum  string
+}
+
+func init() {
+	getCmd.Flags().StringVarP(&downloadFlags.Output, "output", "o", "", "Save the response body to this file")
+	getCmd.Flags().StringVar(&downloadFlags.OutputDir, "output-dir", "", "Directory to save response bodies in, named after the URL unless -o is set")
+	getCmd.Flags().BoolVar(&downloadFlags.Resume, "resume", false, "Resume partially saved files with a Range request")
+	getCmd.Flags().StringVar(&downloadFlags.Checksum, "checksum", "", "Verify saved files against ALGORITHM:HEX (md5, sha1, sha256 or sha512)")
+}
+
+func saving() bool {
+	return downloadFlags.Output != "" || downloadFlags.OutputDir != ""
+}
+
+// downloadPath picks the file for request i of times. With more than one
+// request each gets its own file, numbered before the extension
+// ("build-2.tar.gz"). An -o ending in a slash is a directory to save the
+// file named after the URL in.
+func downloadPath(rawURL string, i, times int) string {
+	name := downloadFlags.Output
+	if name == "" || strings.HasSuffix(name, "/") || strings.HasSuffix(name, string(filepath.Separator)) {
+		urlName := "index.html"
+		if u, err := url.Parse(rawURL); err == nil {
+			if base := path.Base(u.Path); base != "/" && base != "." {
+				urlName = base
+			}
+		}
+		name += urlName
+	}
+	if times > 1 {
+		dir, base := filepath.Split(name)
+		stem, ext := base, ""
+		if dot := strings.Index(base[1:], "."); dot >= 0 {
+			stem, ext = base[:dot+1], base[dot+1:]
+		}
+		name = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i+1, ext))
+	}
+	return filepath.Join(downloadFlags.OutputDir, name)
+}
+
+type checksum struct {
+	algorithm string
+	newHash   func() hash.Hash
+	expected  []byte
+}
+
+func parseChecksum(spec string) (*checksum, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	algorithm, digest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("--checksum expects ALGORITHM:HEX, got %q", spec)
+	}
+	sum := &checksum{algorithm: strings.ToLower(algorithm)}
+	switch sum.algorithm {
+	case "md5":
+		sum.newHash = md5.New
+	case "sha1":
+		sum.newHash = sha1.New
+	case "sha256":
+		sum.newHash = sha256.New
+	case "sha512":
+		sum.newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("unknown checksum algorithm %q", algorithm)
+	}
+	expected, err := hex.DecodeString(digest)
+	if err != nil || len(expected) != sum.newHash().Size() {
+		return nil, fmt.Errorf("invalid %s digest %q", sum.algorithm, digest)
+	}
+	sum.expected = expected
+	return sum, nil
+}
+
+// verify hashes the whole file, so resumed downloads are covered too.
+func (c *checksum) verify(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := c.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, c.expected) {
+		return fmt.Errorf("%s mismatch for %s: got %x, want %x", c.algorithm, file, got, c.expected)
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progress draws one line on stderr summing every download in flight.
+// It's silent when stderr isn't a terminal.
+type progress struct {
+	total   atomic.Int64
+	written atomic.Int64
+	start   time.Time
+	active  bool
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newProgress() *progress {
+	p := &progress{start: time.Now(), active: isTerminal(realStderr())}
+	if p.active {
+		p.stop = make(chan struct{})
+		p.stopped = make(chan struct{})
+		go func() {
+			defer close(p.stopped)
+			ticker := time.NewTicker(200 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					outputMu.Lock()
+					fmt.Fprint(os.Stderr, "\r\x1b[K"+p.line())
+					outputMu.Unlock()
+				case <-p.stop:
+					return
+				}
+			}
+		}()
+	}
+	return p
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.written.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// expect adds n bytes to the total, when the length is known.
+func (p *progress) expect(n int64) {
+	if n > 0 {
+		p.total.Add(n)
+	}
+}
+
+func (p *progress) line() string {
+	written, total := p.written.Load(), p.total.Load()
+	rate := float64(written) / time.Since(p.start).Seconds()
+	if total <= 0 {
+		return fmt.Sprintf("%s (%s/s)", formatBytes(written), formatBytes(int64(rate)))
+	}
+	const width = 30
+	filled := int(float64(width) * float64(written) / float64(total))
+	if filled > width {
+		filled = width
+	}
+	return fmt.Sprintf("[%s%s] %3.0f%% %s / %s (%s/s)", strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		100*float64(written)/float64(total), formatBytes(written), formatBytes(total), formatBytes(int64(rate)))
+}
+
+// println prints a line to stdout without tearing the progress line.
+func (p *progress) println(a ...any) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if p.active {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+	fmt.Println(a...)
+}
+
+func (p *progress) finish() {
+	if !p.active {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+	fmt.Fprint(os.Stderr, "\r\x1b[K")
+}
+
+// contentRangeStart returns the first byte of a "bytes first-last/size"
+// Content-Range header.
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil
+}
+
+// contentRangeTotal returns the size from the "bytes */size" Content-Range
+// of a 416 response.
+func contentRangeTotal(header string) (int64, bool) {
+	_, size, ok := strings.Cut(header, "/")
+	if !ok {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(size, 10, 64)
+	return total, err == nil
+}
+
+// download saves one response to file, resuming it when asked and the
+// server supports ranges.
+func download(client *http.Client, rawURL, file string, sum *checksum, p *progress) error {
+	return fetchFile(client, rawURL, file, sum, p, downloadFlags.Resume)
+}
+
+func fetchFile(client *http.Client, rawURL, file string, sum *checksum, p *progress, resume bool) error {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if resume {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			offset = info.Size()
+		}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	measured, err := measureRequest(client.Do, req)
+	if err != nil {
+		return err
+	}
+	resp := measured.Res
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		if total, ok := contentRangeTotal(resp.Header.Get("Content-Range")); !ok || total != offset {
+			// The local file is larger than the remote one (or the
+			// server won't say), so it isn't a prefix of it.
+			drainAndClose(resp.Body)
+			return fetchFile(client, rawURL, file, sum, p, false)
+		}
+		// Nothing left to fetch: the file is already complete.
+		if sum != nil {
+			if err := sum.verify(file); err != nil {
+				return err
+			}
+		}
+		p.println(fmt.Sprintf("%s already complete (%s)", file, formatBytes(offset)))
+		return nil
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		start, ok := contentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return fmt.Errorf("can't resume %s: server sent Content-Range %q", file, resp.Header.Get("Content-Range"))
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode >= 400:
+		return fmt.Errorf("not saving %s: %s", rawURL, resp.Status)
+	default:
+		// The server ignored the Range header, start over.
+		offset = 0
+	}
+
+	if dir := filepath.Dir(file); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(file, flags, 0644)
+	if err != nil {
+		return err
+	}
+	p.expect(resp.ContentLength)
+	n, err := io.Copy(io.MultiWriter(f, p), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", file, err)
+	}
+
+	if sum != nil {
+		if err := sum.verify(file); err != nil {
+			os.Remove(file)
+			return err
+		}
+	}
+
+	saved := fmt.Sprintf("Saved %s -> %s: %s, %s in %v", rawURL, file, resp.Status, formatBytes(n), time.Since(measured.Start).Round(time.Millisecond))
+	if offset > 0 {
+		saved += fmt.Sprintf(" (resumed at %s)", formatBytes(offset))
+	}
+	if sum != nil {
+		saved += ", " + sum.algorithm + " ok"
+	}
+	p.println(saved)
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadPath(t *testing.T) {
+	defer func(saved string, dir string) {
+		downloadFlags.Output, downloadFlags.OutputDir = saved, dir
+	}(downloadFlags.Output, downloadFlags.OutputDir)
+
+	tests := []struct {
+		output, outputDir string
+		url               string
+		i, times          int
+		want              string
+	}{
+		{"", "", "https://example.com/build.tar.gz", 0, 1, "build.tar.gz"},
+		{"", "", "https://example.com/", 0, 1, "index.html"},
+		{"", "", "https://example.com", 0, 1, "index.html"},
+		{"", "out", "https://example.com/a/b.json?x=1", 0, 1, "out/b.json"},
+		{"", "", "https://example.com/build.tar.gz", 1, 3, "build-2.tar.gz"},
+		{"", "", "https://example.com/", 2, 3, "index-3.html"},
+		{"saved", "", "https://example.com/x", 0, 2, "saved-1"},
+		{".env", "", "https://example.com/x", 0, 2, ".env-1"},
+		{"dir/", "", "https://example.com/file.txt", 0, 1, "dir/file.txt"},
+		{"dir/", "", "https://example.com/file.txt", 1, 2, "dir/file-2.txt"},
+		{"dir/", "", "https://example.com/", 0, 2, "dir/index-1.html"},
+		{"data.bin", "out", "https://example.com/x", 0, 1, "out/data.bin"},
+	}
+	for _, tt := range tests {
+		downloadFlags.Output, downloadFlags.OutputDir = tt.output, tt.outputDir
+		if got := downloadPath(tt.url, tt.i, tt.times); got != filepath.FromSlash(tt.want) {
+			t.Errorf("-o %q --output-dir %q %s (%d of %d): got %s, want %s", tt.output, tt.outputDir, tt.url, tt.i+1, tt.times, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	defer func(resume bool) { downloadFlags.Resume = resume }(downloadFlags.Resume)
+	downloadFlags.Resume = true
+
+	content := []byte(strings.Repeat("0123456789", 100))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		local []byte
+	}{
+		{"missing", nil},
+		{"partial", content[:300]},
+		{"complete", content},
+		{"larger than remote", append(append([]byte{}, content...), "garbage"...)},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "data.bin")
+		if tt.local != nil {
+			os.WriteFile(file, tt.local, 0644)
+		}
+		if err := download(server.Client(), server.URL, file, nil, newProgress()); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got, _ := os.ReadFile(file); !bytes.Equal(got, content) {
+			t.Errorf("%s: saved %d bytes, want the %d of the remote file", tt.name, len(got), len(content))
+		}
+	}
+}
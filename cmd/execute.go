@@ -2,35 +2,263 @@
 package cmd
 
 import (
-	"bufio"
+	"io"
 	"fmt"
 	"os"
 	"github.com/spf13/cobra"
 	"strings"
-	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 	"math"
 	"net/http"
-	"net/http/httptrace"
-	"crypto/tls"
 )
 
 var executeFlags struct {
 	NumWorkers  		int
 	ShowSingleProcesses bool
+	Speed				float64
 }
 
 func init() {
 	executeCmd.Flags().IntVarP(&executeFlags.NumWorkers, "workers", "w", 5, "Number of concurrent go workers")
 	executeCmd.Flags().BoolVar(&executeFlags.ShowSingleProcesses, "s", false, "Shows single processes")
+	executeCmd.Flags().Float64Var(&executeFlags.Speed, "speed", 1, "Replay at= timings this many times faster, 0 to send every line at once")
 	rootCmd.AddCommand(executeCmd)
 }
 
 type lines struct {
 	URL			string
 	NumTimes	int
+	Proxy		string
+	Sign		string
+	Method		string
+	Headers		[]string
+	Body		string
+	Schema		string
+	At			time.Duration
+}
+
+// splitLine splits a line into whitespace separated tokens. Double
+// quotes group words and take \", \\, \n and \r escapes, single quotes
+// group words literally, and {{...}} is always kept whole.
+func splitLine(text string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	inToken := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		case strings.HasPrefix(text[i:], "{{"):
+			end := strings.Index(text[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated {{ in %q", text)
+			}
+			token.WriteString(text[i : i+end+2])
+			i += end + 1
+			inToken = true
+		case c == '"':
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if strings.HasPrefix(text[i:], "{{") {
+					if end := strings.Index(text[i:], "}}"); end >= 0 {
+						token.WriteString(text[i : i+end+2])
+						i += end + 1
+						continue
+					}
+				}
+				if text[i] == '\\' && i+1 < len(text) {
+					switch text[i+1] {
+					case '"', '\\':
+						i++
+					case 'n':
+						i++
+						token.WriteByte('\n')
+						continue
+					case 'r':
+						i++
+						token.WriteByte('\r')
+						continue
+					}
+				}
+				token.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated quote in %q", text)
+			}
+			inToken = true
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %q", text)
+			}
+			token.WriteString(text[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+		default:
+			token.WriteByte(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// quoteToken quotes token for splitLine if it needs it, escaping line
+// breaks so multi-line bodies stay on one line of the suite.
+func quoteToken(token string) string {
+	if token != "" && !strings.ContainsAny(token, " \t\"'\\\n\r") {
+		return token
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	var b strings.Builder
+	b.WriteByte('"')
+	// splitLine copies {{...}} as is, so it mustn't be escaped either.
+	for {
+		start := strings.Index(token, "{{")
+		end := -1
+		if start >= 0 {
+			end = strings.Index(token[start:], "}}")
+		}
+		if end < 0 {
+			b.WriteString(escape.Replace(token))
+			break
+		}
+		b.WriteString(escape.Replace(token[:start]))
+		b.WriteString(token[start : start+end+2])
+		token = token[start+end+2:]
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// formatLine is the inverse of parseLine, writing line in the suite
+// format with only the options that differ from the defaults.
+func formatLine(line lines) string {
+	tokens := []string{quoteToken(line.URL), strconv.Itoa(line.NumTimes)}
+	if line.Method != "" && line.Method != "GET" {
+		tokens = append(tokens, "method="+line.Method)
+	}
+	for _, header := range line.Headers {
+		tokens = append(tokens, quoteToken("header="+header))
+	}
+	if line.Body != "" {
+		tokens = append(tokens, quoteToken("body="+line.Body))
+	}
+	if line.Proxy != "" {
+		tokens = append(tokens, quoteToken("proxy="+line.Proxy))
+	}
+	if line.Sign != "" {
+		tokens = append(tokens, quoteToken("sign="+line.Sign))
+	}
+	if line.Schema != "" {
+		tokens = append(tokens, quoteToken("schema="+line.Schema))
+	}
+	if line.At > 0 {
+		tokens = append(tokens, "at="+line.At.String())
+	}
+	return strings.Join(tokens, " ")
+}
+
+// parseLine reads a line of the form "url numTimes [key=value ...]",
+// split by splitLine. Options:
+//
+//	method=POST              request method (default GET)
+//	header="Name: value"     extra header, repeatable
+//	body='{"a":1}'           request body
+//	proxy=URL                overrides --proxy
+//	sign=none|hmac|aws-sigv4:REGION:SERVICE
+//	                         overrides the request signer
+//	schema=user.json         JSON Schema the response bodies must match,
+//	                         relative to the suite; overrides --schema
+//	at=1.5s                  when to start the line, from the start of the
+//	                         run (scaled by --speed)
+//
+// ${NAME} and {{secret "name"}} are expanded in each token.
+func parseLine(text string) (lines, error) {
+	parts, err := splitLine(text)
+	if err != nil {
+		return lines{}, err
+	}
+	for i, part := range parts {
+		if parts[i], err = interpolate(part); err != nil {
+			return lines{}, err
+		}
+	}
+	if len(parts) < 2 {
+		return lines{}, fmt.Errorf("expected \"url numTimes\", got %q", text)
+	}
+
+	numTimes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return lines{}, fmt.Errorf("error converting numTimes to integer: %w", err)
+	}
+	if numTimes < 1 {
+		return lines{}, fmt.Errorf("numTimes must be at least 1, got %d", numTimes)
+	}
+	url, err := normalizeURL(parts[0])
+	if err != nil {
+		return lines{}, err
+	}
+	line := lines{
+		URL:      url,
+		NumTimes: numTimes,
+		Method:   "GET",
+	}
+
+	for _, option := range parts[2:] {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return lines{}, fmt.Errorf("expected key=value, got %q", option)
+		}
+		switch key {
+		case "proxy":
+			if _, err := parseProxyURL(value); err != nil {
+				return lines{}, err
+			}
+			line.Proxy = value
+		case "method":
+			if value == "" || strings.ContainsAny(value, " \t") {
+				return lines{}, fmt.Errorf("invalid method %q", value)
+			}
+			line.Method = strings.ToUpper(value)
+		case "header":
+			if _, err := parseHeaders([]string{value}); err != nil {
+				return lines{}, err
+			}
+			line.Headers = append(line.Headers, value)
+		case "body":
+			line.Body = value
+		case "sign":
+			signing := transportFlags.Signing
+			if err := signing.override(value); err != nil {
+				return lines{}, err
+			}
+			line.Sign = value
+		case "schema":
+			if value == "" {
+				return lines{}, fmt.Errorf("empty schema")
+			}
+			line.Schema = value
+		case "at":
+			at, err := time.ParseDuration(value)
+			if err != nil || at < 0 {
+				return lines{}, fmt.Errorf("invalid at %q", value)
+			}
+			line.At = at
+		default:
+			return lines{}, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return line, nil
 }
 
 type urlMeasuredResponse struct {
@@ -44,6 +272,7 @@ type urlMeasuredResponse struct {
 	AverageTLSTime 		time.Duration
 	AverageTotalTime 	time.Duration
 	Status 				map[string]int			
+	Record				record
 }
 
 var executeCmd = &cobra.Command{
@@ -56,42 +285,107 @@ var executeCmd = &cobra.Command{
 			fileName = fileName + ".txt"
 		}
 
-		filePath := filepath.Join("executable", fileName)
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := loadContract(); err != nil {
+			fmt.Println("Error reading spec:", err)
+			return
+		}
+		suiteSchema, err := commandSchema()
+		if err != nil {
+			fmt.Println("Error reading schema:", err)
+			return
+		}
 
 
 		if _, err := os.Stat(filePath); err == nil {
-			file, err := os.Open(filePath)
+			fileLines, err := readFileLines(filePath)
 			if err != nil {
-				fmt.Println("Error creating file:", err)
+				fmt.Println("Error reading file:", err)
 				return
 			}
-			defer file.Close()
 
-			scanner := bufio.NewScanner(file)
-			
+			// One shared transport per distinct set of line overrides, so
+			// lines that agree share connections too. Each line still gets
+			// its own session (cookies) on top.
+			transports := make(map[string]http.RoundTripper)
+
 			ch := make(chan urlMeasuredResponse)
 	
+			type readyLine struct {
+				transport http.RoundTripper
+				line      lines
+				schema    *jsonSchema
+			}
+			var ready []readyLine
+
 			var waitGroupLine sync.WaitGroup
-			for scanner.Scan() {
-				waitGroupLine.Add(1)
-				line := scanner.Text() 
-				parts := strings.SplitN(line, " ", 2)
-				
-				numTimes, err := strconv.Atoi(parts[1])
+			for i, line := range fileLines {
+				lineNumber := i + 1
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+
+				addLine, err := parseLine(line)
 				if err != nil {
-					fmt.Println("Error converting numTimes to integer:", err)
+					fmt.Printf("Skipping line %d: %v\n", lineNumber, err)
 					continue
 				}
-				url := parts[0]
-				if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-					url = "http://" + url
+
+				cfg := transportFlags
+				if addLine.Proxy != "" {
+					cfg.Proxy = addLine.Proxy
+				}
+				if addLine.Sign != "" {
+					cfg.Signing.override(addLine.Sign)
+				}
+				key := addLine.Proxy + "\x00" + addLine.Sign
+				transport, ok := transports[key]
+				if !ok {
+					transport, err = newSharedTransport(cfg)
+					if err != nil {
+						fmt.Printf("Skipping line %d: %v\n", lineNumber, err)
+						continue
+					}
+					transports[key] = transport
+				}
+				transport, err = cfg.sessionTransport(transport)
+				if err != nil {
+					fmt.Printf("Skipping line %d: %v\n", lineNumber, err)
+					continue
 				}
 
-				addLine := lines {
-					URL:      url,
-					NumTimes: numTimes,
+				schema := suiteSchema
+				if addLine.Schema != "" {
+					if schema, err = lineSchema(filePath, addLine.Schema); err != nil {
+						fmt.Printf("Skipping line %d: %v\n", lineNumber, err)
+						continue
+					}
 				}
-				go executeLine(addLine, ch, &waitGroupLine)
+
+				ready = append(ready, readyLine{transport, addLine, schema})
+			}
+
+			if executeFlags.Speed < 0 {
+				fmt.Println("--speed can't be negative")
+				return
+			}
+
+			// Start only once every line is parsed, so any secrets they use
+			// are already masked when output begins. Lines with at= wait for
+			// their offset from this common start.
+			start := time.Now()
+			for _, r := range ready {
+				waitGroupLine.Add(1)
+				go func(r readyLine) {
+					if r.line.At > 0 && executeFlags.Speed > 0 {
+						time.Sleep(time.Until(start.Add(time.Duration(float64(r.line.At) / executeFlags.Speed))))
+					}
+					executeLine(r.transport, r.line, r.schema, ch, &waitGroupLine)
+				}(r)
 			}
 
 			go func() {
@@ -99,12 +393,17 @@ var executeCmd = &cobra.Command{
 				close(ch)
 			}()
 
+			violating := 0
 			for response := range ch {
+				violating += response.Record.ViolatingResponses
 				fmt.Println("\nURL: ", response.URL)
+				fmt.Println("Method: ", response.Method)
 				fmt.Println("Number of requests: ", response.NumberOfRequests)
 				fmt.Println("Average DNS Time: ", response.AverageDNSTime)
-				fmt.Println("Average Connect Time: ", response.AverageConnectTime)
-				fmt.Println("Average TLS Time: ", response.AverageTLSTime)
+				if !transportFlags.HTTP3 {
+					fmt.Println("Average Connect Time: ", response.AverageConnectTime)
+					fmt.Println("Average TLS Time: ", response.AverageTLSTime)
+				}
 				fmt.Println("Average Runtime: ", response.AverageTotalTime)
 				fmt.Println("Fastest Runtime: ", response.Fastest)
 				fmt.Println("Slowest Runtiem: ", response.Slowest)
@@ -112,6 +411,12 @@ var executeCmd = &cobra.Command{
 				for status, count := range response.Status {
 					fmt.Printf("%s: %d\n", status, count)
 				}
+				printRecordBreakdown(response.Record)
+			}
+			fmt.Println()
+			printConnectionStats()
+			if violating > 0 {
+				exit(1)
 			}
 		} else if os.IsNotExist(err) {
 			fmt.Println("File does not exist:", fileName)
@@ -123,7 +428,7 @@ var executeCmd = &cobra.Command{
 	},
 }
 
-func executeLine(line lines, ch chan <- urlMeasuredResponse, waitGroupLine *sync.WaitGroup) {
+func executeLine(transport http.RoundTripper, line lines, schema *jsonSchema, ch chan <- urlMeasuredResponse, waitGroupLine *sync.WaitGroup) {
 	defer waitGroupLine.Done()
 	var wg sync.WaitGroup
 	result := record{
@@ -136,7 +441,7 @@ func executeLine(line lines, ch chan <- urlMeasuredResponse, waitGroupLine *sync
 	times := line.NumTimes
 	for i := 0; i < line.NumTimes; i++ {
 		wg.Add(1)
-		go executeRequest(line.URL, &wg, chMeasured)
+		go executeRequest(transport, line, schema, &wg, chMeasured)
 	}
 	go func() {
 		wg.Wait()
@@ -145,22 +450,12 @@ func executeLine(line lines, ch chan <- urlMeasuredResponse, waitGroupLine *sync
 
 
 	for response := range chMeasured {
-		result.TotalDNSTimeRecorded += response.DNS
-		result.TotalConnectTimeRecorded += response.Connect
-		result.TotalTLSTimeRecorded += response.TLS
-		result.TotalTimeRecorded += response.TotalTime
-		result.Status[response.Status]++
-		if result.Fastest > response.TotalTime {
-			result.Fastest = response.TotalTime
-		}
-		if result.Slowest < response.TotalTime {
-			result.Slowest = response.TotalTime
-		}
+		result.add(response)
 	}
 	
 	newURLMeasuredResponse := urlMeasuredResponse{
 		URL : 					line.URL,
-		Method : 				"GET",
+		Method : 				line.Method,
 		Fastest : 				result.Fastest,
 		Slowest : 				result.Slowest,
 		NumberOfRequests : 		line.NumTimes,
@@ -169,49 +464,37 @@ func executeLine(line lines, ch chan <- urlMeasuredResponse, waitGroupLine *sync
 		AverageTLSTime : 		result.TotalTLSTimeRecorded / time.Duration(times),
 		AverageTotalTime : 		result.TotalTimeRecorded / time.Duration(times),
 		Status : 				result.Status,
+		Record : 				result,
 	}
 	ch <- newURLMeasuredResponse
 }	
 
 
-func executeRequest(url string, wg *sync.WaitGroup, chMeasured chan <- measuredResponse) {
+func executeRequest(transport http.RoundTripper, line lines, schema *jsonSchema, wg *sync.WaitGroup, chMeasured chan <- measuredResponse) {
 	defer wg.Done()
-	req, _ := http.NewRequest("GET", url, nil)
-	measured := measuredResponse{}
-	var start, connect, dns, tlsHandshake time.Time
-
-	trace := &httptrace.ClientTrace{
-		DNSStart: func(dsi httptrace.DNSStartInfo) { dns = time.Now() },
-		DNSDone: func(ddi httptrace.DNSDoneInfo) {
-			measured.DNS = time.Since(dns)
-		},
-		ConnectStart: func(network, addr string) { connect = time.Now() },
-		ConnectDone: func(network, addr string, err error) {
-			measured.Connect = time.Since(connect)
-		},
-		TLSHandshakeStart: func() { tlsHandshake = time.Now() },
-		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
-			measured.TLS = time.Since(tlsHandshake)
-		},
-		GotFirstResponseByte: func() {
-			measured.TotalTime = time.Since(start)
-		},
-	}
-
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-	start = time.Now()
-
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	var body io.Reader
+	if line.Body != "" {
+		body = strings.NewReader(line.Body)
+	}
+	req, err := http.NewRequest(line.Method, line.URL, body)
+	if err != nil {
+		return
+	}
+	header, _ := parseHeaders(line.Headers)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	measured, err := measureRequest(transport.RoundTrip, req)
 	if err != nil {
 		return
 	}
-	measured.Start = start
-	measured.Res = resp
-	measured.Status = resp.Status
-	defer resp.Body.Close()
+	resp := measured.Res
+	finishResponse(req, &measured, schema)
 	
 	if executeFlags.ShowSingleProcesses {
-		fmt.Printf("Status: %s\nTotal Time: %v\n\n", resp.Status, measured.TotalTime)
+		printViolations(measured)
+		fmt.Printf("Status: %s\nTotal Time: %v\nBody Size: %s\n\n", resp.Status, measured.TotalTime, bodySummary(measured))
 	}
 	chMeasured <- measured
 }
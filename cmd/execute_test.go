@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLineErrors(t *testing.T) {
+	tests := []struct{ line, err string }{
+		{"http://example.com", "expected \"url numTimes\""},
+		{"http://example.com x", "converting numTimes"},
+		{"http://example.com 0", "at least 1"},
+		{"http://example.com -3", "at least 1"},
+		{"http://example.com 1 method", "expected key=value"},
+		{"http://example.com 1 colour=red", "unknown option"},
+		{"http://example.com 1 method=", "invalid method"},
+		{"http://example.com 1 header=nocolon", "invalid header"},
+		{"http://example.com 1 at=soon", "invalid at"},
+		{"http://example.com 1 at=-1s", "invalid at"},
+		{"http://example.com 1 schema=", "empty schema"},
+		{"http://example.com 1 sign=rsa", "unknown signer"},
+		{`http://example.com 1 "body=open`, "unterminated quote"},
+		{"http://example.com 1 body={{secret", "unterminated {{"},
+		{"http://example.com 1 header=X-Token:${HPGO_TEST_UNDEFINED}", "undefined variable"},
+	}
+	for _, tt := range tests {
+		_, err := parseLine(tt.line)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.line, err, tt.err)
+		}
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"  a \tb  ", []string{"a", "b"}},
+		{`a "b c" 'd e'`, []string{"a", "b c", "d e"}},
+		{`"body={\"a\":\"x\\y\"}"`, []string{`body={"a":"x\y"}`}},
+		{`"a\nb\r\n" 'c\nd'`, []string{"a\nb\r\n", `c\nd`}},
+		{`"\d\t"`, []string{`\d\t`}},
+		{`header=Authorization:"Bearer x"`, []string{"header=Authorization:Bearer x"}},
+		{`header=X:{{secret "a b"}} c`, []string{`header=X:{{secret "a b"}}`, "c"}},
+		{`"header=X: {{secret "a b"}}"`, []string{`header=X: {{secret "a b"}}`}},
+		{`'' ""`, []string{"", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitLine(tt.line)
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("%s: got %q, %v, want %q", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatLineRoundTrip(t *testing.T) {
+	tests := []lines{
+		{URL: "http://example.com", NumTimes: 1},
+		{URL: "http://example.com/a b?q='x'", NumTimes: 3, Method: "POST"},
+		{URL: "http://example.com", NumTimes: 1, Method: "POST", Body: "{\n  \"a\": 1,\n  \"b\": \"x\\ny\"\n}\r\n"},
+		{URL: "http://example.com", NumTimes: 2, Headers: []string{"Content-Type: text/plain", `X-Path: C:\tmp\new`}, Body: `'quoted' "double"`},
+		{URL: "http://example.com", NumTimes: 1, Proxy: "http://proxy:8080", Sign: "aws-sigv4:us-east-1:s3", Schema: "schemas/user.json", At: 1500 * time.Millisecond},
+	}
+	for _, want := range tests {
+		text := formatLine(want)
+		if strings.ContainsAny(text, "\n\r") {
+			t.Errorf("%q: formatted over several lines: %q", want.Body, text)
+		}
+		got, err := parseLine(text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if got.URL != want.URL || got.NumTimes != want.NumTimes || got.Method != orDefault(want.Method, "GET") ||
+			strings.Join(got.Headers, "\n") != strings.Join(want.Headers, "\n") || got.Body != want.Body ||
+			got.Proxy != want.Proxy || got.Sign != want.Sign || got.Schema != want.Schema || got.At != want.At {
+			t.Errorf("%s:\n got %+v\nwant %+v", text, got, want)
+		}
+	}
+
+	// Templates are written back untouched, so they still expand.
+	text := formatLine(lines{URL: "http://example.com", NumTimes: 1, Headers: []string{`Authorization: Bearer {{secret "token"}}`}})
+	if tokens, err := splitLine(text); err != nil || len(tokens) != 3 || tokens[2] != `header=Authorization: Bearer {{secret "token"}}` {
+		t.Errorf("%s: got %q, %v", text, tokens, err)
+	}
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
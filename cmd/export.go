@@ -0,0 +1,494 @@
+// cmd/export.go
+//
+// Turns requests into curl and httpie commands or a Go program, so a
+// request found with hpgo can be reproduced without it. --print-curl
+// prints the curl command for each request a command sends, 'export'
+// converts a whole suite. Transport options (auth, TLS, proxy, timeout,
+// protocol) are carried over where the target has an equivalent and
+// listed as comments where it doesn't. Secrets are masked.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var printCurl bool
+
+var exportFlags struct {
+	Format string
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&printCurl, "print-curl", false, "Print the equivalent curl command for each request (to stderr)")
+	exportCmd.Flags().StringVar(&exportFlags.Format, "format", "curl", "Output format: curl, httpie or go")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// snippet is one request plus the transport options that shape it.
+type snippet struct {
+	Method   string
+	URL      string
+	Headers  []string
+	Body     string
+	NumTimes int
+	cfg      transportConfig
+}
+
+func lineSnippet(line lines, cfg transportConfig) snippet {
+	if line.Proxy != "" {
+		cfg.Proxy = line.Proxy
+	}
+	if line.Sign != "" {
+		cfg.Signing.override(line.Sign)
+	}
+	headers := append([]string(nil), line.Headers...)
+	// --header and profile headers, unless the line sets them itself.
+	for _, header := range cfg.Headers {
+		key, _, _ := strings.Cut(header, ":")
+		if !hasHeader(headers, strings.TrimSpace(key)) {
+			headers = append(headers, header)
+		}
+	}
+	return snippet{Method: line.Method, URL: line.URL, Headers: headers, Body: line.Body, NumTimes: line.NumTimes, cfg: cfg}
+}
+
+// requestSnippet captures req, replacing its body so it can still be sent.
+func requestSnippet(req *http.Request, cfg transportConfig) (snippet, error) {
+	s := snippet{Method: req.Method, URL: req.URL.String(), NumTimes: 1, cfg: cfg}
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			s.Headers = append(s.Headers, key+": "+value)
+		}
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return s, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		s.Body = string(body)
+	}
+	return s, nil
+}
+
+// untranslated lists the options target has no equivalent for.
+func (s snippet) untranslated(target string) []string {
+	var notes []string
+	auth, tls := s.cfg.Auth, s.cfg.TLS
+	if auth.OAuth2TokenURL != "" {
+		notes = append(notes, "OAuth2 client credentials from "+auth.OAuth2TokenURL+", add the token as a header")
+	}
+	if auth.Digest != "" && target == "go" {
+		notes = append(notes, "Digest authentication")
+	}
+	if s.cfg.Signing.AWSSigV4 != "" && target != "curl" {
+		notes = append(notes, "AWS SigV4 signing ("+s.cfg.Signing.AWSSigV4+")")
+	}
+	if s.cfg.Signing.HMACKey != "" {
+		notes = append(notes, "HMAC request signing")
+	}
+	if tls.ServerName != "" || tls.MinVersion != "" || tls.Ciphers != "" {
+		notes = append(notes, "TLS server name, minimum version and ciphers")
+	}
+	if target == "go" && (tls.CACert != "" || tls.Cert != "") {
+		notes = append(notes, "TLS CA and client certificates")
+	}
+	if target != "curl" && (s.cfg.HTTP2 || s.cfg.H2C || s.cfg.HTTP3) {
+		notes = append(notes, "forced HTTP version")
+	}
+	if s.cfg.UnixSocket != "" && target != "curl" {
+		notes = append(notes, "unix socket "+s.cfg.UnixSocket)
+	}
+	if len(s.cfg.ConnectTo) > 0 || len(s.cfg.Resolve) > 0 {
+		notes = append(notes, "--connect-to and --resolve")
+	}
+	if s.cfg.Cookies.enabled() {
+		notes = append(notes, "cookie jar")
+	}
+	return notes
+}
+
+func (s snippet) follows() bool {
+	return s.cfg.Redirects.Follow && !s.cfg.Redirects.NoFollow
+}
+
+// shellQuote quotes s for sh when it needs quoting.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellCommand(args []string, notes []string) string {
+	var b strings.Builder
+	for _, note := range notes {
+		b.WriteString("# not translated: " + note + "\n")
+	}
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(" \\\n  ")
+		}
+		b.WriteString(arg)
+	}
+	return b.String()
+}
+
+func (s snippet) curl() string {
+	args := []string{"curl"}
+	switch s.Method {
+	case "HEAD":
+		args = append(args, "-I")
+	case "GET":
+		// --data-raw alone would make it a POST.
+		if s.Body != "" {
+			args = append(args, "-X GET")
+		}
+	default:
+		if s.Method != "POST" || s.Body == "" {
+			args = append(args, "-X "+s.Method)
+		}
+	}
+	args = append(args, shellQuote(s.URL))
+	for _, header := range s.Headers {
+		args = append(args, "-H "+shellQuote(header))
+	}
+	if s.Body != "" {
+		args = append(args, "--data-raw "+shellQuote(s.Body))
+	}
+
+	cfg := s.cfg
+	switch {
+	case cfg.Auth.Basic != "":
+		args = append(args, "-u "+shellQuote(cfg.Auth.Basic))
+	case cfg.Auth.Digest != "":
+		args = append(args, "--digest -u "+shellQuote(cfg.Auth.Digest))
+	case cfg.Auth.Bearer != "":
+		args = append(args, "-H "+shellQuote("Authorization: Bearer "+cfg.Auth.Bearer))
+	}
+	if cfg.Signing.AWSSigV4 != "" {
+		region, service, _ := strings.Cut(cfg.Signing.AWSSigV4, ":")
+		args = append(args, "--aws-sigv4 "+shellQuote("aws:amz:"+region+":"+service))
+		if cfg.Signing.AWSAccessKey != "" {
+			args = append(args, "-u "+shellQuote(cfg.Signing.AWSAccessKey+":"+cfg.Signing.AWSSecretKey))
+		}
+	}
+	if cfg.TLS.Insecure {
+		args = append(args, "-k")
+	}
+	if cfg.TLS.CACert != "" {
+		args = append(args, "--cacert "+shellQuote(cfg.TLS.CACert))
+	}
+	if cfg.TLS.Cert != "" {
+		args = append(args, "--cert "+shellQuote(cfg.TLS.Cert))
+	}
+	if cfg.TLS.Key != "" {
+		args = append(args, "--key "+shellQuote(cfg.TLS.Key))
+	}
+	switch {
+	case cfg.HTTP2:
+		args = append(args, "--http2")
+	case cfg.H2C:
+		args = append(args, "--http2-prior-knowledge")
+	case cfg.HTTP11:
+		args = append(args, "--http1.1")
+	case cfg.HTTP3:
+		args = append(args, "--http3")
+	}
+	if cfg.Proxy != "" {
+		args = append(args, "-x "+shellQuote(cfg.Proxy))
+	}
+	if cfg.UnixSocket != "" {
+		args = append(args, "--unix-socket "+shellQuote(cfg.UnixSocket))
+	}
+	if cfg.Compressed {
+		args = append(args, "--compressed")
+	}
+	if cfg.Timeout > 0 {
+		args = append(args, "--max-time "+strconv.FormatFloat(cfg.Timeout.Seconds(), 'f', -1, 64))
+	}
+	if s.follows() {
+		args = append(args, "-L")
+		if cfg.Redirects.MaxRedirects > 0 {
+			args = append(args, "--max-redirs "+strconv.Itoa(cfg.Redirects.MaxRedirects))
+		}
+	}
+	return shellCommand(args, s.untranslated("curl"))
+}
+
+func (s snippet) httpie() string {
+	args := []string{"http"}
+	cfg := s.cfg
+	if s.follows() {
+		args = append(args, "--follow")
+		if cfg.Redirects.MaxRedirects > 0 {
+			args = append(args, "--max-redirects="+strconv.Itoa(cfg.Redirects.MaxRedirects))
+		}
+	}
+	switch {
+	case cfg.Auth.Basic != "":
+		args = append(args, "--auth "+shellQuote(cfg.Auth.Basic))
+	case cfg.Auth.Digest != "":
+		args = append(args, "--auth-type digest --auth "+shellQuote(cfg.Auth.Digest))
+	case cfg.Auth.Bearer != "":
+		args = append(args, "--auth-type bearer --auth "+shellQuote(cfg.Auth.Bearer))
+	}
+	switch {
+	case cfg.TLS.Insecure:
+		args = append(args, "--verify=no")
+	case cfg.TLS.CACert != "":
+		args = append(args, "--verify="+shellQuote(cfg.TLS.CACert))
+	}
+	if cfg.TLS.Cert != "" {
+		args = append(args, "--cert="+shellQuote(cfg.TLS.Cert))
+	}
+	if cfg.TLS.Key != "" {
+		args = append(args, "--cert-key="+shellQuote(cfg.TLS.Key))
+	}
+	if cfg.Proxy != "" {
+		args = append(args, "--proxy="+shellQuote("http:"+cfg.Proxy), "--proxy="+shellQuote("https:"+cfg.Proxy))
+	}
+	if cfg.Timeout > 0 {
+		args = append(args, "--timeout="+strconv.FormatFloat(cfg.Timeout.Seconds(), 'f', -1, 64))
+	}
+	if s.Body != "" {
+		// Send the body as is rather than through httpie's JSON items.
+		args = append(args, "--raw="+shellQuote(s.Body))
+	}
+	args = append(args, s.Method, shellQuote(s.URL))
+	for _, header := range s.Headers {
+		key, value, _ := strings.Cut(header, ":")
+		args = append(args, shellQuote(strings.TrimSpace(key)+":"+strings.TrimSpace(value)))
+	}
+	return shellCommand(args, s.untranslated("httpie"))
+}
+
+// goProgram writes a Go program that sends each snippet in turn. The
+// transport options come from the first one, like a suite run.
+func goProgram(snippets []snippet) (string, error) {
+	cfg := snippets[0].cfg
+	imports := map[string]bool{"fmt": true, "io": true, "log": true, "net/http": true, "os": true}
+	var b strings.Builder
+
+	var notes []string
+	for _, s := range snippets {
+		for _, note := range s.untranslated("go") {
+			if !containsString(notes, note) {
+				notes = append(notes, note)
+			}
+		}
+	}
+
+	b.WriteString("func main() {\n")
+	for _, note := range notes {
+		b.WriteString("// Not translated: " + note + "\n")
+	}
+	var transport []string
+	if cfg.TLS.Insecure {
+		imports["crypto/tls"] = true
+		transport = append(transport, "TLSClientConfig: &tls.Config{InsecureSkipVerify: true},")
+	}
+	if cfg.Proxy != "" {
+		imports["net/url"] = true
+		fmt.Fprintf(&b, "proxyURL, err := url.Parse(%q)\nif err != nil {\nlog.Fatal(err)\n}\n", cfg.Proxy)
+		transport = append(transport, "Proxy: http.ProxyURL(proxyURL),")
+	}
+	if cfg.HTTP2 {
+		transport = append(transport, "ForceAttemptHTTP2: true,")
+	}
+	if cfg.HTTP11 {
+		imports["crypto/tls"] = true
+		transport = append(transport, "TLSNextProto: map[string]func(string, *tls.Conn) http.RoundTripper{},")
+	}
+	b.WriteString("client := &http.Client{\n")
+	if len(transport) > 0 {
+		b.WriteString("Transport: &http.Transport{\n" + strings.Join(transport, "\n") + "\n},\n")
+	}
+	if cfg.Timeout > 0 {
+		imports["time"] = true
+		fmt.Fprintf(&b, "Timeout: %d * time.Millisecond,\n", cfg.Timeout.Milliseconds())
+	}
+	if !snippets[0].follows() {
+		b.WriteString("CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },\n")
+	}
+	b.WriteString("}\n\n")
+
+	for i, s := range snippets {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if s.NumTimes > 1 {
+			fmt.Fprintf(&b, "// Sent %d times by the suite.\n", s.NumTimes)
+		}
+		body := "nil"
+		if s.Body != "" {
+			imports["strings"] = true
+			body = "strings.NewReader(" + goString(s.Body) + ")"
+		}
+		fmt.Fprintf(&b, "req%d, err := http.NewRequest(%q, %q, %s)\nif err != nil {\nlog.Fatal(err)\n}\n", i+1, s.Method, s.URL, body)
+		for _, header := range s.Headers {
+			key, value, _ := strings.Cut(header, ":")
+			fmt.Fprintf(&b, "req%d.Header.Add(%q, %q)\n", i+1, strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+		switch {
+		case s.cfg.Auth.Basic != "":
+			user, pass, _ := strings.Cut(s.cfg.Auth.Basic, ":")
+			fmt.Fprintf(&b, "req%d.SetBasicAuth(%q, %q)\n", i+1, user, pass)
+		case s.cfg.Auth.Bearer != "":
+			fmt.Fprintf(&b, "req%d.Header.Set(\"Authorization\", %q)\n", i+1, "Bearer "+s.cfg.Auth.Bearer)
+		}
+		fmt.Fprintf(&b, "send(client, req%d)\n", i+1)
+	}
+	b.WriteString("}\n\n")
+	b.WriteString(`func send(client *http.Client, req *http.Request) {
+resp, err := client.Do(req)
+if err != nil {
+log.Fatal(err)
+}
+defer resp.Body.Close()
+fmt.Println(req.Method, req.URL, resp.Status)
+io.Copy(os.Stdout, resp.Body)
+fmt.Println()
+}
+`)
+
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, strconv.Quote(name))
+	}
+	sort.Strings(names)
+	source := "package main\n\nimport (\n" + strings.Join(names, "\n") + "\n)\n\n" + b.String()
+	formatted, err := format.Source([]byte(source))
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+// goString quotes s as a Go literal, raw when that stays readable.
+func goString(s string) string {
+	if !strings.Contains(s, "`") && strconv.CanBackquote(strings.ReplaceAll(s, "\n", "")) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// withPrintCurl prints each distinct request as a curl command before
+// sending it. Stress runs send the same request many times, so every
+// command is printed once.
+func withPrintCurl(rt http.RoundTripper, cfg transportConfig) http.RoundTripper {
+	if !printCurl {
+		return rt
+	}
+	return &printCurlTransport{rt: rt, cfg: cfg}
+}
+
+type printCurlTransport struct {
+	rt      http.RoundTripper
+	cfg     transportConfig
+	printed sync.Map
+}
+
+func (t *printCurlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	s, err := requestSnippet(clone, t.cfg)
+	if err != nil {
+		return nil, err
+	}
+	command := maskSecrets(s.curl())
+	if _, seen := t.printed.LoadOrStore(command, true); !seen {
+		outputMu.Lock()
+		fmt.Fprintln(os.Stderr, command)
+		outputMu.Unlock()
+	}
+	return t.rt.RoundTrip(clone)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [fileName]",
+	Short: "Prints a suite as curl or httpie commands, or as a Go program",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath, err := suitePath(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		text, err := readFileLines(filePath)
+		if err != nil {
+			fmt.Println("Error reading file:", err)
+			return
+		}
+
+		var snippets []snippet
+		for i, line := range text {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			parsed, err := parseLine(line)
+			if err != nil {
+				fmt.Printf("%s:%d: %v\n", filePath, i+1, err)
+				exit(1)
+			}
+			snippets = append(snippets, lineSnippet(parsed, transportFlags))
+		}
+		if len(snippets) == 0 {
+			fmt.Println("No requests in", filePath)
+			return
+		}
+
+		switch exportFlags.Format {
+		case "curl", "httpie":
+			for i, s := range snippets {
+				if i > 0 {
+					fmt.Println()
+				}
+				if s.NumTimes > 1 {
+					fmt.Printf("# sent %d times by the suite\n", s.NumTimes)
+				}
+				if exportFlags.Format == "curl" {
+					fmt.Println(s.curl())
+				} else {
+					fmt.Println(s.httpie())
+				}
+			}
+		case "go":
+			program, err := goProgram(snippets)
+			if err != nil {
+				fmt.Println("Error generating Go code:", err)
+				exit(1)
+			}
+			fmt.Print(program)
+		default:
+			fmt.Printf("unknown format %q, expected curl, httpie or go\n", exportFlags.Format)
+			exit(1)
+		}
+	},
+}
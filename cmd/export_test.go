@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnippetCurl(t *testing.T) {
+	tests := []struct {
+		name string
+		s    snippet
+		want string
+	}{
+		{"get", snippet{Method: "GET", URL: "https://api/x"}, "curl https://api/x"},
+		{"get with body", snippet{Method: "GET", URL: "https://api/x", Body: "q"},
+			"curl -X GET https://api/x --data-raw q"},
+		{"head", snippet{Method: "HEAD", URL: "https://api/x"}, "curl -I https://api/x"},
+		{"post", snippet{Method: "POST", URL: "https://api/x", Body: `{"a": "it's"}`, Headers: []string{"Content-Type: application/json"}},
+			`curl https://api/x -H 'Content-Type: application/json' --data-raw '{"a": "it'\''s"}'`},
+		{"post without body", snippet{Method: "POST", URL: "https://api/x?a=1&b=2"}, "curl -X POST 'https://api/x?a=1&b=2'"},
+		{"delete", snippet{Method: "DELETE", URL: "https://api/x", Body: "a"}, "curl -X DELETE https://api/x --data-raw a"},
+		{"options", snippet{Method: "GET", URL: "https://api/x", cfg: transportConfig{
+			Auth:      authOptions{Basic: "user:pass"},
+			TLS:       tlsOptions{Insecure: true, CACert: "ca.pem"},
+			HTTP2:     true,
+			Proxy:     "http://proxy:8080",
+			Timeout:   1500 * time.Millisecond,
+			Redirects: redirectOptions{Follow: true, MaxRedirects: 3},
+		}}, "curl https://api/x -u user:pass -k --cacert ca.pem --http2 -x http://proxy:8080 --max-time 1.5 -L --max-redirs 3"},
+		{"sigv4", snippet{Method: "GET", URL: "https://api/x", cfg: transportConfig{
+			Signing: signingOptions{AWSSigV4: "us-east-1:s3", AWSAccessKey: "AK", AWSSecretKey: "SK"},
+		}}, "curl https://api/x --aws-sigv4 aws:amz:us-east-1:s3 -u AK:SK"},
+		{"untranslated", snippet{Method: "GET", URL: "https://api/x", cfg: transportConfig{
+			Signing: signingOptions{HMACKey: "k"},
+			Cookies: cookieOptions{Enabled: true},
+		}}, "# not translated: HMAC request signing\n# not translated: cookie jar\ncurl https://api/x"},
+	}
+	for _, tt := range tests {
+		got := strings.ReplaceAll(tt.s.curl(), " \\\n  ", " ")
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnippetHTTPie(t *testing.T) {
+	tests := []struct {
+		name string
+		s    snippet
+		want string
+	}{
+		{"get", snippet{Method: "GET", URL: "https://api/x", Headers: []string{"X-A:  1", "X-B: a b"}},
+			"http GET https://api/x X-A:1 'X-B:a b'"},
+		{"body", snippet{Method: "POST", URL: "https://api/x", Body: `{"a": 1}`},
+			`http --raw='{"a": 1}' POST https://api/x`},
+		{"options", snippet{Method: "GET", URL: "https://api/x", cfg: transportConfig{
+			Auth:      authOptions{Bearer: "t"},
+			TLS:       tlsOptions{CACert: "ca.pem", Cert: "c.pem", Key: "k.pem"},
+			Proxy:     "http://proxy",
+			Timeout:   2 * time.Second,
+			Redirects: redirectOptions{Follow: true},
+		}}, "http --follow --auth-type bearer --auth t --verify=ca.pem --cert=c.pem --cert-key=k.pem " +
+			"--proxy=http:http://proxy --proxy=https:http://proxy --timeout=2 GET https://api/x"},
+		{"untranslated", snippet{Method: "GET", URL: "https://api/x", cfg: transportConfig{
+			HTTP3:      true,
+			UnixSocket: "/tmp/s",
+			Signing:    signingOptions{AWSSigV4: "us-east-1:s3"},
+		}}, "# not translated: AWS SigV4 signing (us-east-1:s3)\n# not translated: forced HTTP version\n" +
+			"# not translated: unix socket /tmp/s\nhttp GET https://api/x"},
+	}
+	for _, tt := range tests {
+		got := strings.ReplaceAll(tt.s.httpie(), " \\\n  ", " ")
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGoProgram(t *testing.T) {
+	cfg := transportConfig{
+		TLS:     tlsOptions{Insecure: true, Cert: "c.pem"},
+		Proxy:   "http://proxy",
+		Timeout: time.Second,
+		Auth:    authOptions{Basic: "user:pass"},
+	}
+	program, err := goProgram([]snippet{
+		{Method: "GET", URL: "https://api/x", NumTimes: 3, cfg: cfg},
+		{Method: "POST", URL: "https://api/y", Headers: []string{"Content-Type: text/plain"}, Body: "a `b`\n", cfg: cfg},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"crypto/tls"`, `"net/url"`, `"strings"`, `"time"`,
+		"// Not translated: TLS CA and client certificates",
+		"TLSClientConfig: &tls.Config{InsecureSkipVerify: true},",
+		`proxyURL, err := url.Parse("http://proxy")`,
+		"1000 * time.Millisecond,",
+		"CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },",
+		"// Sent 3 times by the suite.",
+		`req1, err := http.NewRequest("GET", "https://api/x", nil)`,
+		`req1.SetBasicAuth("user", "pass")`,
+		`req2, err := http.NewRequest("POST", "https://api/y", strings.NewReader("a ` + "`b`" + `\n"))`,
+		`req2.Header.Add("Content-Type", "text/plain")`,
+		"send(client, req2)",
+	} {
+		if !strings.Contains(program, want) {
+			t.Errorf("program is missing %s:\n%s", want, program)
+		}
+	}
+
+	program, err = goProgram([]snippet{{Method: "GET", URL: "https://api/x", cfg: transportConfig{Redirects: redirectOptions{Follow: true}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(program, "CheckRedirect") || strings.Contains(program, "Not translated") || strings.Contains(program, `"strings"`) {
+		t.Errorf("unexpected options in:\n%s", program)
+	}
+}
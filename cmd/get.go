@@ -10,9 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"github.com/spf13/cobra"
 )
@@ -42,31 +40,71 @@ var getCmd = &cobra.Command{
             }
         }
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-            url = "http://" + url
-        }
+		url, err = normalizeURL(url)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 	
+		if err := validateRenderFlags(); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		sum, err := parseChecksum(downloadFlags.Checksum)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if sum != nil && !saving() {
+			fmt.Println("--checksum needs -o or --output-dir")
+			return
+		}
+
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
+		}
+
 		var wg sync.WaitGroup
 
+		if saving() {
+			p := newProgress()
+			for i := 0; i < times; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if err := download(client, url, downloadPath(url, i, times), sum, p); err != nil {
+						p.println("error saving response:", err)
+					}
+				}(i)
+			}
+			wg.Wait()
+			p.finish()
+			fmt.Println("All requests completed.")
+			return
+		}
+
 		for i := 0; i < times; i++ {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				resp, err := http.Get(url)
+				req, _ := http.NewRequest("GET", url, nil)
+				measured, err := measureRequest(client.Do, req)
 				if err != nil {
 					fmt.Println("error making GET request: ", err)
 					return
 				}
+				resp := measured.Res
 				defer resp.Body.Close()
 	
-				fmt.Println("Status:", resp.Status)
-				fmt.Println("Header: ", resp.Header)
-				fmt.Println("Body:")
-				_, err = io.Copy(os.Stdout, resp.Body)
+				body, err := io.ReadAll(resp.Body)
 				if err != nil {
 					fmt.Println("error reading response body: ", err)
 					return
 				}
+				printResponse(measured, body)
 			}()
 		}
 		wg.Wait()
@@ -75,5 +113,6 @@ var getCmd = &cobra.Command{
 }
 
 func init() {
+	addRenderFlags(getCmd)
 	rootCmd.AddCommand(getCmd)
 }
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
@@ -46,8 +45,16 @@ var headCmd = &cobra.Command{
 			}
 		}
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			url = "http://" + url
+		url, err = normalizeURL(url)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
 		}
 
 		var wg sync.WaitGroup
@@ -55,7 +62,7 @@ var headCmd = &cobra.Command{
 
 		for i := 0; i < times; i++ {
 			wg.Add(1)
-			go headRequest(url, &wg, ch)
+			go headRequest(client, url, &wg, ch)
 		}
 
 		go func() {
@@ -73,16 +80,17 @@ var headCmd = &cobra.Command{
 	},
 }
 
-func headRequest(url string, wg *sync.WaitGroup, ch chan<- string) {
+func headRequest(client *http.Client, url string, wg *sync.WaitGroup, ch chan<- string) {
 	defer wg.Done()
 	req, _ := http.NewRequest("HEAD", url, nil)
 
-	resp, err := http.DefaultClient.Do(req)
+	measured, err := measureRequest(client.Do, req)
 	if err != nil {
 		ch <- fmt.Sprintf("Error: %v", err)
 		return
 	}
+	resp := measured.Res
 	defer resp.Body.Close()
 
-	ch <- fmt.Sprintf("Status: %s, Headers: %v", resp.Status, resp.Header)
+	ch <- fmt.Sprintf("Status: %s, Protocol: %s, Headers: %v", resp.Status, resp.Proto, resp.Header)
 }
@@ -0,0 +1,87 @@
+// cmd/headers.go
+//
+// Request defaults every command shares: extra headers from --header (or
+// the profile) and an overall timeout covering the request, its
+// redirects and reading the body.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&transportFlags.Headers, "header", nil, "\"Name: value\" header sent with every request (repeatable)")
+	rootCmd.PersistentFlags().DurationVar(&transportFlags.Timeout, "timeout", 0, "Time limit for each request including reading the body, 0 for none")
+}
+
+func parseHeaders(raw []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, h := range raw {
+		key, value, ok := strings.Cut(h, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+		}
+		header.Add(key, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// withDefaults returns rt adding the configured headers and timeout, or
+// rt itself when there are none.
+func withDefaults(rt http.RoundTripper, cfg transportConfig) (http.RoundTripper, error) {
+	header, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if len(header) == 0 && cfg.Timeout <= 0 {
+		return rt, nil
+	}
+	return &defaultsTransport{rt: rt, header: header, timeout: cfg.Timeout}, nil
+}
+
+type defaultsTransport struct {
+	rt      http.RoundTripper
+	header  http.Header
+	timeout time.Duration
+}
+
+func (t *defaultsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	clone := req.Clone(ctx)
+	// Headers set by the command itself take precedence.
+	for key, values := range t.header {
+		if clone.Header.Get(key) == "" {
+			clone.Header[key] = values
+		}
+	}
+
+	resp, err := t.rt.RoundTrip(clone)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout once the body has been closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
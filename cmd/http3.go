@@ -0,0 +1,97 @@
+// cmd/http3.go
+//
+// HTTP/3 transport. QUIC folds the TCP connect and TLS handshake into
+// one phase, which httptrace knows nothing about, so the dialer times it
+// itself and reports it as measuredResponse.QUIC.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func newHTTP3Transport(cfg transportConfig, tlsCfg *tls.Config) (*http3.Transport, error) {
+	rewrite, err := cfg.connectTarget()
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := cfg.hostResolver()
+	if err != nil {
+		return nil, err
+	}
+	return &http3.Transport{
+		TLSClientConfig:    tlsCfg,
+		DisableCompression: true,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			return dialQUIC(ctx, resolver, rewrite(addr), tlsCfg, cfg)
+		},
+	}, nil
+}
+
+// dialQUIC resolves addr with the request's context (so the DNS trace
+// hooks still fire) and waits for the handshake to finish so its full
+// duration can be recorded on the request that triggered the dial.
+func dialQUIC(ctx context.Context, resolver *hostResolver, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %q", addr)
+	}
+	ips, err := resolver.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	udpAddr := &net.UDPAddr{IP: ips[0], Port: port}
+
+	start := time.Now()
+	conn, err := quic.DialAddrEarly(ctx, udpAddr.String(), tlsCfg, cfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-conn.HandshakeComplete():
+	case <-ctx.Done():
+		conn.CloseWithError(0, "")
+		return nil, ctx.Err()
+	}
+
+	connStats.Opened.Add(1)
+	if measured := measuredFromContext(ctx); measured != nil {
+		measured.QUIC = time.Since(start)
+	}
+	counted := &countedQUICConn{EarlyConnection: conn}
+	go func() {
+		// Idle timeouts and the server closing the connection.
+		<-conn.Context().Done()
+		counted.closed()
+	}()
+	return counted, nil
+}
+
+// countedQUICConn is countedConn for QUIC: closing it locally counts
+// right away, so the stats printed after closeIdleConnections include it.
+type countedQUICConn struct {
+	quic.EarlyConnection
+	once sync.Once
+}
+
+func (c *countedQUICConn) closed() {
+	c.once.Do(func() { connStats.Closed.Add(1) })
+}
+
+func (c *countedQUICConn) CloseWithError(code quic.ApplicationErrorCode, desc string) error {
+	c.closed()
+	return c.EarlyConnection.CloseWithError(code, desc)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// testCertificate is a self-signed certificate for 127.0.0.1.
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestHTTP3Transport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{testCertificate(t)}}),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, r.Proto)
+		}),
+	}
+	go server.Serve(conn)
+	defer server.Close()
+
+	transport, err := newBaseTransport(transportConfig{HTTP3: true, KeepAlive: true, TLS: tlsOptions{Insecure: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "https://" + conn.LocalAddr().String() + "/"
+	opened, closed := connStats.Opened.Load(), connStats.Closed.Load()
+
+	for i, wantReused := range []bool{false, true} {
+		req, _ := http.NewRequest("GET", url, nil)
+		measured, err := measureRequest(transport.RoundTrip, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(measured.Res.Body)
+		measured.Res.Body.Close()
+
+		if measured.Proto != "HTTP/3.0" || string(body) != "HTTP/3.0" {
+			t.Errorf("request %d: got proto %q, server saw %q", i, measured.Proto, body)
+		}
+		if measured.Reused != wantReused {
+			t.Errorf("request %d: reused = %v, want %v", i, measured.Reused, wantReused)
+		}
+		if !wantReused && measured.QUIC <= 0 {
+			t.Errorf("request %d: no QUIC handshake time recorded", i)
+		}
+		if measured.TLSVersion != "TLS 1.3" {
+			t.Errorf("request %d: TLS version %q", i, measured.TLSVersion)
+		}
+	}
+
+	closeIdleConnections()
+	if got := connStats.Opened.Load() - opened; got != 1 {
+		t.Errorf("opened %d connections, want 1", got)
+	}
+	if got := connStats.Closed.Load() - closed; got != 1 {
+		t.Errorf("closed %d connections, want 1", got)
+	}
+}
+
+func TestQUICHandshakeAverage(t *testing.T) {
+	r := record{Status: map[string]int{}}
+	for _, handshake := range []time.Duration{10 * time.Millisecond, 0, 20 * time.Millisecond, 0} {
+		r.add(measuredResponse{QUIC: handshake, Status: "200 OK"})
+	}
+	output := captureStdout(t, func() { printRecordBreakdown(r) })
+	if !strings.Contains(output, "Average QUIC Handshake Runtime: 15ms\n") {
+		t.Errorf("got %q", output)
+	}
+}
@@ -0,0 +1,116 @@
+// cmd/import.go
+//
+// 'import' converts requests from other tools into suite lines. Each
+// converter returns the lines it could build plus notes about anything
+// it had to drop, which are printed after the import.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type importResult struct {
+	lines []lines
+	notes []string
+}
+
+func (r *importResult) note(format string, args ...any) {
+	r.notes = append(r.notes, fmt.Sprintf(format, args...))
+}
+
+// readImportSource reads a file, or stdin for "-".
+func readImportSource(source string) ([]byte, error) {
+	if source == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(source)
+}
+
+// writeImport appends the converted lines to the suite and reports what
+// wasn't translated.
+func writeImport(source, suite string, result importResult) {
+	filePath, err := suitePath(suite)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		fmt.Println("Error creating directory:", err)
+		return
+	}
+
+	var b strings.Builder
+	if content, err := os.ReadFile(filePath); err == nil && len(content) > 0 && content[len(content)-1] != '\n' {
+		b.WriteString("\n")
+	}
+	for i, line := range result.lines {
+		text := formatLine(line)
+		if strings.Contains(text, "${") || strings.Contains(text, "{{") {
+			result.note("request %d contains ${...} or {{...}}, which execute will interpolate", i+1)
+		}
+		b.WriteString(text + "\n")
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Println("Error opening file:", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(b.String()); err != nil {
+		fmt.Println("Error writing to file:", err)
+		return
+	}
+
+	fmt.Printf("Imported %d requests from %s into %s\n", len(result.lines), source, filePath)
+	if len(result.notes) > 0 {
+		fmt.Println("Not translated:")
+		for _, note := range result.notes {
+			fmt.Println("  -", note)
+		}
+	}
+}
+
+func newImportCmd(use, short string, convert func(data []byte) (importResult, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [source] [fileName]",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := readImportSource(args[0])
+			if err != nil {
+				fmt.Println("Error reading source:", err)
+				return
+			}
+			result, err := convert(data)
+			if err != nil {
+				fmt.Println("Error converting source:", err)
+				return
+			}
+			writeImport(args[0], args[1], result)
+		},
+	}
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Converts curl commands, HAR files and Postman collections into a suite",
+	Long: "Converts curl commands, HAR files and Postman collections into a suite, " +
+		"appending to it if it already exists. Use - as the source to read stdin.",
+}
+
+func init() {
+	importCmd.AddCommand(
+		newImportCmd("curl", "Imports curl commands, one request per command", importCurl),
+		newImportCmd("har", "Imports the requests captured in a HAR file", importHAR),
+		newImportCmd("postman", "Imports a Postman collection (v2.0 or v2.1)", importPostman),
+	)
+	rootCmd.AddCommand(importCmd)
+}
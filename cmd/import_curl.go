@@ -0,0 +1,333 @@
+// cmd/import_curl.go
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// commandBreak separates commands in shellSplit's output.
+const commandBreak = "\n"
+
+// shellSplit splits shell text into words the way sh would for a simple
+// command: quotes, backslash escapes and line continuations. Unquoted
+// newlines, ';', '|' and '&&' come back as commandBreak.
+func shellSplit(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	flush := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			i++
+			if text[i] == '\n' {
+				continue
+			}
+			if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteByte(text[i])
+			inWord = true
+		case c == '#' && !inWord:
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '\n' || c == ';' || c == '|' || c == '&':
+			flush()
+			words = append(words, commandBreak)
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(text[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(text) && text[i+1] == '\'':
+			// $'...' with C style escapes.
+			i += 2
+			for ; i < len(text) && text[i] != '\''; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+					switch text[i] {
+					case 'n':
+						word.WriteByte('\n')
+					case 't':
+						word.WriteByte('\t')
+					case 'r':
+						word.WriteByte('\r')
+					default:
+						word.WriteByte(text[i])
+					}
+					continue
+				}
+				word.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\\\"$`\n", text[i+1]) >= 0 {
+					i++
+					if text[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(text[i])
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
+
+// Short curl options that take a value, which may be attached ("-XPOST").
+const curlShortWithValue = "XHduAebomxFTErwcDKCyYztUQP"
+
+type curlOption struct {
+	name, value string
+	hasValue    bool
+}
+
+// splitCurlCluster splits a cluster of short options like -sSL. The
+// first one that takes a value takes the rest of the cluster ("-sXPOST"),
+// or the next argument when it's last ("-sX POST").
+func splitCurlCluster(arg string) []curlOption {
+	var options []curlOption
+	for j := 1; j < len(arg); j++ {
+		option := curlOption{name: "-" + arg[j:j+1]}
+		if strings.IndexByte(curlShortWithValue, arg[j]) >= 0 {
+			option.value, option.hasValue = arg[j+1:], j+1 < len(arg)
+			return append(options, option)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// curlEscape percent-encodes s like curl's --data-urlencode, which
+// leaves only unreserved characters and writes spaces as %20.
+func curlEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func basicAuthHeader(credentials string) string {
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+}
+
+func hasHeader(headers []string, name string) bool {
+	for _, header := range headers {
+		key, _, _ := strings.Cut(header, ":")
+		if strings.EqualFold(strings.TrimSpace(key), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func importCurl(data []byte) (importResult, error) {
+	var result importResult
+	words, err := shellSplit(string(data))
+	if err != nil {
+		return result, err
+	}
+
+	var command []string
+	number := 0
+	convert := func() {
+		if len(command) > 0 && command[0] == "curl" {
+			number++
+			if line, ok := convertCurl(command[1:], number, &result); ok {
+				result.lines = append(result.lines, line)
+			}
+		}
+		command = nil
+	}
+	for _, word := range words {
+		if word == commandBreak {
+			convert()
+			continue
+		}
+		command = append(command, word)
+	}
+	convert()
+	if number == 0 {
+		return result, fmt.Errorf("no curl commands found")
+	}
+	return result, nil
+}
+
+func convertCurl(args []string, number int, result *importResult) (lines, bool) {
+	line := lines{NumTimes: 1}
+	var data []string
+	get, isJSON := false, false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		options := []curlOption{{name: arg}}
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			options[0] = curlOption{name, value, hasValue}
+		case strings.HasPrefix(arg, "-") && len(arg) > 2:
+			options = splitCurlCluster(arg)
+		}
+		for _, option := range options {
+			name, value, hasValue := option.name, option.value, option.hasValue
+			takeValue := func() string {
+				if hasValue {
+					return value
+				}
+				if i+1 < len(args) {
+					i++
+					return args[i]
+				}
+				result.note("curl %d: %s is missing its value", number, name)
+				return ""
+			}
+
+			switch name {
+			case "-X", "--request":
+				line.Method = strings.ToUpper(takeValue())
+			case "-H", "--header":
+				header := takeValue()
+				if key, value, ok := strings.Cut(header, ":"); ok && strings.TrimSpace(value) != "" {
+					line.Headers = append(line.Headers, strings.TrimSpace(key)+": "+strings.TrimSpace(value))
+				} else {
+					result.note("curl %d: header %q removes or empties a header", number, header)
+				}
+			case "-d", "--data", "--data-ascii", "--data-binary":
+				value := takeValue()
+				if strings.HasPrefix(value, "@") {
+					result.note("curl %d: body read from file %s", number, value[1:])
+					continue
+				}
+				data = append(data, value)
+			case "--data-urlencode":
+				// content, =content or name=content, with content encoded;
+				// @file and name@file read it from a file.
+				value := takeValue()
+				split := strings.IndexAny(value, "=@")
+				switch {
+				case split >= 0 && value[split] == '@':
+					result.note("curl %d: body read from file %s", number, value[split+1:])
+				case split == 0:
+					data = append(data, curlEscape(value[1:]))
+				case split > 0:
+					data = append(data, value[:split+1]+curlEscape(value[split+1:]))
+				default:
+					data = append(data, curlEscape(value))
+				}
+			case "--data-raw":
+				data = append(data, takeValue())
+			case "--json":
+				data = append(data, takeValue())
+				isJSON = true
+			case "-u", "--user":
+				line.Headers = append(line.Headers, basicAuthHeader(takeValue()))
+			case "-A", "--user-agent":
+				line.Headers = append(line.Headers, "User-Agent: "+takeValue())
+			case "-e", "--referer":
+				line.Headers = append(line.Headers, "Referer: "+takeValue())
+			case "-b", "--cookie":
+				cookie := takeValue()
+				if strings.Contains(cookie, "=") {
+					line.Headers = append(line.Headers, "Cookie: "+cookie)
+				} else {
+					result.note("curl %d: cookie file %s (use --cookie-jar)", number, cookie)
+				}
+			case "-x", "--proxy":
+				line.Proxy = takeValue()
+			case "-I", "--head":
+				line.Method = "HEAD"
+			case "-G", "--get":
+				get = true
+			case "--url":
+				line.URL = takeValue()
+			case "-k", "--insecure":
+				result.note("curl %d: --insecure applies to the whole run, pass it to execute", number)
+			case "--compressed":
+				result.note("curl %d: --compressed applies to the whole run, pass it to execute", number)
+			case "-m", "--max-time":
+				result.note("curl %d: --max-time %s (use --timeout)", number, takeValue())
+			case "-o", "--output", "-T", "--upload-file", "-E", "--cert", "-r", "--range":
+				result.note("curl %d: %s %s", number, name, takeValue())
+			case "-F", "--form":
+				result.note("curl %d: multipart form field %q", number, takeValue())
+			case "-L", "--location", "-s", "--silent", "-S", "--show-error", "-v", "--verbose",
+				"-i", "--include", "-f", "--fail", "-#", "--progress-bar", "-N", "--no-buffer":
+				// Output and redirect behaviour, nothing to carry over.
+			default:
+				if strings.HasPrefix(name, "-") && len(name) == 2 && strings.IndexByte(curlShortWithValue, name[1]) >= 0 {
+					result.note("curl %d: unsupported option %s %s", number, name, takeValue())
+				} else if strings.HasPrefix(arg, "-") {
+					result.note("curl %d: unsupported option %s", number, name)
+				} else if line.URL == "" {
+					line.URL = arg
+				} else {
+					result.note("curl %d: extra argument %q", number, arg)
+				}
+			}
+		}
+	}
+
+	if line.URL == "" {
+		result.note("curl %d: no URL, skipped", number)
+		return line, false
+	}
+	if len(data) > 0 {
+		body := strings.Join(data, "&")
+		if get {
+			separator := "?"
+			if strings.Contains(line.URL, "?") {
+				separator = "&"
+			}
+			line.URL += separator + body
+		} else {
+			line.Body = body
+			if line.Method == "" {
+				line.Method = http.MethodPost
+			}
+			switch {
+			case isJSON:
+				if !hasHeader(line.Headers, "Content-Type") {
+					line.Headers = append(line.Headers, "Content-Type: application/json")
+				}
+				if !hasHeader(line.Headers, "Accept") {
+					line.Headers = append(line.Headers, "Accept: application/json")
+				}
+			case !hasHeader(line.Headers, "Content-Type"):
+				line.Headers = append(line.Headers, "Content-Type: application/x-www-form-urlencoded")
+			}
+		}
+	}
+	if line.Method == "" {
+		line.Method = http.MethodGet
+	}
+	return line, true
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+		err  string
+	}{
+		{"curl  -s\thttps://a", []string{"curl", "-s", "https://a"}, ""},
+		{`curl -d 'a b' "c \"d\" \$e \x"`, []string{"curl", "-d", "a b", `c "d" $e \x`}, ""},
+		{"curl \\\n  -X POST \\\r\n  https://a", []string{"curl", "-X", "POST", "https://a"}, ""},
+		{`curl $'a\nb\'' x\ y`, []string{"curl", "a\nb'", "x y"}, ""},
+		{"curl a; curl b | x && y", []string{"curl", "a", commandBreak, "curl", "b", commandBreak, "x", commandBreak, commandBreak, "y"}, ""},
+		{"# comment\ncurl a#b", []string{commandBreak, "curl", "a#b"}, ""},
+		{"curl 'a", nil, "unterminated single quote"},
+		{`curl "a`, nil, "unterminated double quote"},
+	}
+	for _, tt := range tests {
+		got, err := shellSplit(tt.text)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: got error %v, want %s", tt.text, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%q: got %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestConvertCurl(t *testing.T) {
+	tests := []struct {
+		args    string
+		method  string
+		url     string
+		headers []string
+		body    string
+		notes   []string
+	}{
+		{"https://api/x", "GET", "https://api/x", nil, "", nil},
+		{"-sX POST https://api/x", "POST", "https://api/x", nil, "", nil},
+		{"-sSLXPUT https://api/x", "PUT", "https://api/x", nil, "", nil},
+		{"-skH 'X-A: 1' https://api/x", "GET", "https://api/x", []string{"X-A: 1"},
+			"", []string{"curl 1: --insecure applies to the whole run, pass it to execute"}},
+		{"-sw '%{http_code}' https://api/x", "GET", "https://api/x", nil,
+			"", []string{"curl 1: unsupported option -w %{http_code}"}},
+		{"--request=delete --url https://api/x", "DELETE", "https://api/x", nil, "", nil},
+		{"-d a=1 --data b=2 https://api/x", "POST", "https://api/x",
+			[]string{"Content-Type: application/x-www-form-urlencoded"}, "a=1&b=2", nil},
+		{"--data-urlencode 'q=a b&c' --data-urlencode =x/y --data-urlencode 'é+' https://api/x", "POST", "https://api/x",
+			[]string{"Content-Type: application/x-www-form-urlencoded"}, "q=a%20b%26c&x%2Fy&%C3%A9%2B", nil},
+		{"-G --data-urlencode 'q=a b' https://api/x?p=1", "GET", "https://api/x?p=1&q=a%20b", nil, "", nil},
+		{"--data-urlencode name@file.txt https://api/x", "GET", "https://api/x", nil,
+			"", []string{"curl 1: body read from file file.txt"}},
+		{`--json '{"a":1}' -u user:pass https://api/x`, "POST", "https://api/x",
+			[]string{"Authorization: Basic dXNlcjpwYXNz", "Content-Type: application/json", "Accept: application/json"}, `{"a":1}`, nil},
+		{"https://api/x https://api/y", "GET", "https://api/x", nil, "", []string{`curl 1: extra argument "https://api/y"`}},
+		{"https://api/x -X", "GET", "https://api/x", nil, "", []string{"curl 1: -X is missing its value"}},
+	}
+	for _, tt := range tests {
+		args, err := shellSplit(tt.args)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.args, err)
+		}
+		var result importResult
+		line, ok := convertCurl(args, 1, &result)
+		if strings.Join(result.notes, "|") != strings.Join(tt.notes, "|") {
+			t.Errorf("%s: notes %q, want %q", tt.args, result.notes, tt.notes)
+		}
+		if !ok {
+			if tt.url != "" {
+				t.Errorf("%s: skipped", tt.args)
+			}
+			continue
+		}
+		if line.Method != tt.method || line.URL != tt.url || line.Body != tt.body ||
+			strings.Join(line.Headers, "|") != strings.Join(tt.headers, "|") {
+			t.Errorf("%s:\n got %s %s %q %q\nwant %s %s %q %q", tt.args,
+				line.Method, line.URL, line.Headers, line.Body, tt.method, tt.url, tt.headers, tt.body)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// cmd/import_har.go
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request harRequest `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harRequest struct {
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	Headers []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	PostData *struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Params   []struct {
+			Name     string `json:"name"`
+			Value    string `json:"value"`
+			FileName string `json:"fileName"`
+		} `json:"params"`
+	} `json:"postData"`
+}
+
+// Headers the transport sets itself; copying them would only get in the way.
+var harSkippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"accept-encoding":   true,
+	"transfer-encoding": true,
+}
+
+func importHAR(data []byte) (importResult, error) {
+	var result importResult
+	var file harFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return result, fmt.Errorf("not a HAR file: %w", err)
+	}
+	if len(file.Log.Entries) == 0 {
+		return result, fmt.Errorf("no entries in HAR file")
+	}
+
+	for i, entry := range file.Log.Entries {
+		req := entry.Request
+		if req.URL == "" {
+			result.note("entry %d: no URL, skipped", i+1)
+			continue
+		}
+		line := lines{URL: req.URL, Method: strings.ToUpper(req.Method), NumTimes: 1}
+		if line.Method == "" {
+			line.Method = "GET"
+		}
+		for _, header := range req.Headers {
+			if strings.HasPrefix(header.Name, ":") || harSkippedHeaders[strings.ToLower(header.Name)] {
+				continue
+			}
+			line.Headers = append(line.Headers, header.Name+": "+header.Value)
+		}
+
+		if post := req.PostData; post != nil {
+			switch {
+			case post.Text != "":
+				line.Body = post.Text
+			case strings.HasPrefix(post.MimeType, "multipart/"):
+				result.note("entry %d: multipart body", i+1)
+			case len(post.Params) > 0:
+				form := url.Values{}
+				for _, param := range post.Params {
+					if param.FileName != "" {
+						result.note("entry %d: file upload %s", i+1, param.FileName)
+						continue
+					}
+					form.Add(param.Name, param.Value)
+				}
+				line.Body = form.Encode()
+			}
+			if line.Body != "" && post.MimeType != "" && !hasHeader(line.Headers, "Content-Type") {
+				line.Headers = append(line.Headers, "Content-Type: "+post.MimeType)
+			}
+		}
+		result.lines = append(result.lines, line)
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportHAR(t *testing.T) {
+	tests := []struct {
+		request string
+		method  string
+		url     string
+		headers []string
+		body    string
+		notes   []string
+	}{
+		{`{"url": "https://api/x"}`, "GET", "https://api/x", nil, "", nil},
+		{`{"method": "get", "url": "https://api/x", "headers": [{"name": ":authority", "value": "api"},
+			{"name": "Host", "value": "api"}, {"name": "accept-encoding", "value": "gzip"}, {"name": "X-A", "value": "1"}]}`,
+			"GET", "https://api/x", []string{"X-A: 1"}, "", nil},
+		{`{"method": "POST", "url": "https://api/x", "postData": {"mimeType": "application/json", "text": "{\"a\":1}"}}`,
+			"POST", "https://api/x", []string{"Content-Type: application/json"}, `{"a":1}`, nil},
+		{`{"method": "POST", "url": "https://api/x", "headers": [{"name": "content-type", "value": "text/plain"}],
+			"postData": {"mimeType": "application/json", "text": "a"}}`,
+			"POST", "https://api/x", []string{"content-type: text/plain"}, "a", nil},
+		{`{"method": "POST", "url": "https://api/x", "postData": {"mimeType": "application/x-www-form-urlencoded",
+			"params": [{"name": "q", "value": "a b"}, {"name": "f", "fileName": "f.txt"}]}}`,
+			"POST", "https://api/x", []string{"Content-Type: application/x-www-form-urlencoded"}, "q=a+b",
+			[]string{"entry 1: file upload f.txt"}},
+		{`{"method": "POST", "url": "https://api/x", "postData": {"mimeType": "multipart/form-data; boundary=b",
+			"params": [{"name": "q", "value": "a"}]}}`,
+			"POST", "https://api/x", nil, "", []string{"entry 1: multipart body"}},
+	}
+	for _, tt := range tests {
+		result, err := importHAR([]byte(`{"log": {"entries": [{"request": ` + tt.request + `}]}}`))
+		if err != nil {
+			t.Errorf("%s: %v", tt.request, err)
+			continue
+		}
+		if strings.Join(result.notes, "|") != strings.Join(tt.notes, "|") {
+			t.Errorf("%s: notes %q, want %q", tt.request, result.notes, tt.notes)
+		}
+		line := result.lines[0]
+		if line.Method != tt.method || line.URL != tt.url || line.Body != tt.body ||
+			strings.Join(line.Headers, "|") != strings.Join(tt.headers, "|") {
+			t.Errorf("%s:\n got %s %s %q %q\nwant %s %s %q %q", tt.request,
+				line.Method, line.URL, line.Headers, line.Body, tt.method, tt.url, tt.headers, tt.body)
+		}
+	}
+}
+
+func TestImportHARSkipsEntriesWithoutURL(t *testing.T) {
+	result, err := importHAR([]byte(`{"log": {"entries": [{"request": {"method": "GET"}}, {"request": {"url": "https://api/x"}}]}}`))
+	if err != nil || len(result.lines) != 1 || result.lines[0].URL != "https://api/x" ||
+		strings.Join(result.notes, "|") != "entry 1: no URL, skipped" {
+		t.Errorf("got %+v, %q, %v", result.lines, result.notes, err)
+	}
+	if _, err := importHAR([]byte(`{"log": {"entries": []}}`)); err == nil || err.Error() != "no entries in HAR file" {
+		t.Errorf("empty HAR: got %v", err)
+	}
+}
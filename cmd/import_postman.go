@@ -0,0 +1,266 @@
+// cmd/import_postman.go
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type postmanCollection struct {
+	Info struct {
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem is a request or, when it has items of its own, a folder.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Auth    *postmanAuth    `json:"auth"`
+	Request json.RawMessage `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    json.RawMessage   `json:"url"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		Options    struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+	Auth *postmanAuth `json:"auth"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// postmanAuth holds the v2.1 form (a list of key/values per type) or the
+// v2.0 form (an object per type).
+type postmanAuth struct {
+	Type   string                     `json:"type"`
+	Fields map[string]json.RawMessage `json:"-"`
+}
+
+func (a *postmanAuth) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if t, ok := raw["type"]; ok {
+		if err := json.Unmarshal(t, &a.Type); err != nil {
+			return err
+		}
+	}
+	a.Fields = raw
+	return nil
+}
+
+// field returns a value of the auth's type, e.g. "token" for bearer.
+func (a *postmanAuth) field(name string) string {
+	raw, ok := a.Fields[a.Type]
+	if !ok {
+		return ""
+	}
+	var list []postmanKeyValue
+	if json.Unmarshal(raw, &list) == nil {
+		for _, kv := range list {
+			if kv.Key == name {
+				return kv.Value
+			}
+		}
+		return ""
+	}
+	var object map[string]string
+	if json.Unmarshal(raw, &object) == nil {
+		return object[name]
+	}
+	return ""
+}
+
+var postmanVariable = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// postmanVariables turns {{name}} into ${name}, which execute reads from
+// the environment or .env.
+type postmanVariables struct {
+	result *importResult
+	seen   map[string]bool
+}
+
+func (v *postmanVariables) convert(s string) string {
+	return postmanVariable.ReplaceAllStringFunc(s, func(match string) string {
+		original := strings.TrimSpace(match[2 : len(match)-2])
+		name := []byte(original)
+		for i, c := range name {
+			if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+				name[i] = '_'
+			}
+		}
+		if !v.seen[original] {
+			v.seen[original] = true
+			v.result.note("variable {{%s}} is now ${%s}, set it in the environment or .env", original, name)
+		}
+		return "${" + string(name) + "}"
+	})
+}
+
+// convertEscaped is convert for query strings and form bodies: the text
+// around the variables is escaped, the variables themselves are kept.
+func (v *postmanVariables) convertEscaped(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range postmanVariable.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:match[0]]))
+		b.WriteString(v.convert(s[match[0]:match[1]]))
+		last = match[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
+
+func importPostman(data []byte) (importResult, error) {
+	var result importResult
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return result, fmt.Errorf("not a Postman collection: %w", err)
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.") {
+		return result, fmt.Errorf("unsupported collection schema %s, export it as v2.1", collection.Info.Schema)
+	}
+
+	variables := &postmanVariables{result: &result, seen: make(map[string]bool)}
+	for _, variable := range collection.Variable {
+		if variable.Value != "" {
+			result.note("collection variable %s defaults to %q", variable.Key, variable.Value)
+		}
+	}
+
+	var walk func(items []postmanItem, path string, auth *postmanAuth)
+	walk = func(items []postmanItem, path string, auth *postmanAuth) {
+		for _, item := range items {
+			name := strings.TrimPrefix(path+"/"+item.Name, "/")
+			itemAuth := auth
+			if item.Auth != nil {
+				itemAuth = item.Auth
+			}
+			if len(item.Request) == 0 {
+				walk(item.Item, name, itemAuth)
+				continue
+			}
+			if line, ok := convertPostman(item.Request, name, itemAuth, variables, &result); ok {
+				result.lines = append(result.lines, line)
+			}
+		}
+	}
+	walk(collection.Item, "", collection.Auth)
+	if len(result.lines) == 0 {
+		return result, fmt.Errorf("no requests in collection")
+	}
+	return result, nil
+}
+
+func convertPostman(raw json.RawMessage, name string, auth *postmanAuth, variables *postmanVariables, result *importResult) (lines, bool) {
+	var req postmanRequest
+	// A request can be just its URL.
+	var rawURL string
+	if json.Unmarshal(raw, &rawURL) == nil {
+		req.URL, _ = json.Marshal(rawURL)
+	} else if err := json.Unmarshal(raw, &req); err != nil {
+		result.note("%s: %v, skipped", name, err)
+		return lines{}, false
+	}
+
+	if json.Unmarshal(req.URL, &rawURL) != nil {
+		var object struct {
+			Raw string `json:"raw"`
+		}
+		json.Unmarshal(req.URL, &object)
+		rawURL = object.Raw
+	}
+	if rawURL == "" {
+		result.note("%s: no URL, skipped", name)
+		return lines{}, false
+	}
+
+	line := lines{URL: variables.convert(rawURL), Method: strings.ToUpper(req.Method), NumTimes: 1}
+	if line.Method == "" {
+		line.Method = "GET"
+	}
+	for _, header := range req.Header {
+		if header.Disabled {
+			continue
+		}
+		line.Headers = append(line.Headers, variables.convert(header.Key+": "+header.Value))
+	}
+
+	if body := req.Body; body != nil {
+		switch body.Mode {
+		case "raw":
+			line.Body = variables.convert(body.Raw)
+			if body.Options.Raw.Language == "json" && !hasHeader(line.Headers, "Content-Type") {
+				line.Headers = append(line.Headers, "Content-Type: application/json")
+			}
+		case "urlencoded":
+			var pairs []string
+			for _, kv := range body.URLEncoded {
+				if !kv.Disabled {
+					pairs = append(pairs, variables.convertEscaped(kv.Key)+"="+variables.convertEscaped(kv.Value))
+				}
+			}
+			line.Body = strings.Join(pairs, "&")
+			if !hasHeader(line.Headers, "Content-Type") {
+				line.Headers = append(line.Headers, "Content-Type: application/x-www-form-urlencoded")
+			}
+		case "":
+		default:
+			result.note("%s: %s body", name, body.Mode)
+		}
+	}
+
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "noauth", "":
+		case "bearer":
+			line.Headers = append(line.Headers, variables.convert("Authorization: Bearer "+auth.field("token")))
+		case "basic":
+			username, password := auth.field("username"), auth.field("password")
+			if strings.Contains(username+password, "{{") {
+				// Can't encode a variable; leave it to the user.
+				result.note("%s: basic auth uses variables, set --basic instead", name)
+			} else {
+				line.Headers = append(line.Headers, basicAuthHeader(username+":"+password))
+			}
+		case "apikey":
+			key, value := auth.field("key"), auth.field("value")
+			if auth.field("in") == "query" {
+				separator := "?"
+				if strings.Contains(line.URL, "?") {
+					separator = "&"
+				}
+				line.URL += separator + variables.convertEscaped(key) + "=" + variables.convertEscaped(value)
+			} else {
+				line.Headers = append(line.Headers, variables.convert(key+": "+value))
+			}
+		default:
+			result.note("%s: %s auth", name, auth.Type)
+		}
+	}
+	return line, true
+}
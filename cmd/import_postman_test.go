@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportPostman(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		auth    string
+		method  string
+		url     string
+		headers []string
+		body    string
+		notes   []string
+	}{
+		{"plain URL", `"https://api/x"`, "", "GET", "https://api/x", nil, "", nil},
+		{"variables", `{"method": "post", "url": {"raw": "{{base url}}/x"},
+			"header": [{"key": "X-A", "value": "{{a}}"}, {"key": "X-B", "value": "b", "disabled": true}],
+			"body": {"mode": "raw", "raw": "{\"a\": \"{{a}}\"}", "options": {"raw": {"language": "json"}}}}`, "",
+			"POST", "${base_url}/x", []string{"X-A: ${a}", "Content-Type: application/json"}, `{"a": "${a}"}`,
+			[]string{"variable {{base url}} is now ${base_url}, set it in the environment or .env",
+				"variable {{a}} is now ${a}, set it in the environment or .env"}},
+		{"urlencoded", `{"method": "POST", "url": "https://api/x", "body": {"mode": "urlencoded", "urlencoded": [
+			{"key": "token", "value": "{{token}}"}, {"key": "q", "value": "a b&{{x}}/c"}, {"key": "off", "value": "1", "disabled": true}]}}`, "",
+			"POST", "https://api/x", []string{"Content-Type: application/x-www-form-urlencoded"}, "token=${token}&q=a+b%26${x}%2Fc",
+			[]string{"variable {{token}} is now ${token}, set it in the environment or .env",
+				"variable {{x}} is now ${x}, set it in the environment or .env"}},
+		{"other body", `{"url": "https://api/x", "body": {"mode": "formdata"}}`, "",
+			"GET", "https://api/x", nil, "", []string{"r: formdata body"}},
+		{"bearer v2.1", `{"url": "https://api/x"}`, `{"type": "bearer", "bearer": [{"key": "token", "value": "{{t}}"}]}`,
+			"GET", "https://api/x", []string{"Authorization: Bearer ${t}"}, "",
+			[]string{"variable {{t}} is now ${t}, set it in the environment or .env"}},
+		{"basic v2.0", `{"url": "https://api/x"}`, `{"type": "basic", "basic": {"username": "user", "password": "pass"}}`,
+			"GET", "https://api/x", []string{"Authorization: Basic dXNlcjpwYXNz"}, "", nil},
+		{"basic with variables", `{"url": "https://api/x"}`, `{"type": "basic", "basic": {"username": "{{u}}", "password": "p"}}`,
+			"GET", "https://api/x", nil, "", []string{"r: basic auth uses variables, set --basic instead"}},
+		{"apikey in query", `{"url": "https://api/x?a=1"}`,
+			`{"type": "apikey", "apikey": [{"key": "key", "value": "api key"}, {"key": "value", "value": "{{k}}&1"}, {"key": "in", "value": "query"}]}`,
+			"GET", "https://api/x?a=1&api+key=${k}%261", nil, "",
+			[]string{"variable {{k}} is now ${k}, set it in the environment or .env"}},
+		{"apikey in header", `{"url": "https://api/x"}`,
+			`{"type": "apikey", "apikey": [{"key": "key", "value": "X-Key"}, {"key": "value", "value": "k"}]}`,
+			"GET", "https://api/x", []string{"X-Key: k"}, "", nil},
+		{"request auth wins", `{"url": "https://api/x", "auth": {"type": "noauth"}}`,
+			`{"type": "bearer", "bearer": [{"key": "token", "value": "t"}]}`, "GET", "https://api/x", nil, "", nil},
+		{"other auth", `{"url": "https://api/x"}`, `{"type": "oauth2"}`, "GET", "https://api/x", nil, "", []string{"r: oauth2 auth"}},
+	}
+	for _, tt := range tests {
+		collection := `{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+			"item": [{"name": "folder", "item": [{"name": "r", "request": ` + tt.request + `}]}]`
+		if tt.auth != "" {
+			collection += `, "auth": ` + tt.auth
+		}
+		result, err := importPostman([]byte(collection + "}"))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		// Notes name the request by its folder path.
+		notes := strings.ReplaceAll(strings.Join(result.notes, "|"), "folder/r:", "r:")
+		if notes != strings.Join(tt.notes, "|") {
+			t.Errorf("%s: notes %q, want %q", tt.name, result.notes, tt.notes)
+		}
+		line := result.lines[0]
+		if line.Method != tt.method || line.URL != tt.url || line.Body != tt.body ||
+			strings.Join(line.Headers, "|") != strings.Join(tt.headers, "|") {
+			t.Errorf("%s:\n got %s %s %q %q\nwant %s %s %q %q", tt.name,
+				line.Method, line.URL, line.Headers, line.Body, tt.method, tt.url, tt.headers, tt.body)
+		}
+	}
+}
+
+func TestImportPostmanErrors(t *testing.T) {
+	tests := []struct {
+		collection string
+		err        string
+	}{
+		{`{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`,
+			"unsupported collection schema https://schema.getpostman.com/json/collection/v1.0.0/collection.json, export it as v2.1"},
+		{`{"item": [{"name": "empty", "item": []}]}`, "no requests in collection"},
+		{`{"item": [{"name": "r", "request": {"method": "GET"}}]}`, "no requests in collection"},
+	}
+	for _, tt := range tests {
+		if _, err := importPostman([]byte(tt.collection)); err == nil || err.Error() != tt.err {
+			t.Errorf("%s: got %v, want %s", tt.collection, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// cmd/list.go
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the txt files in the workspace, including nested folders",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := workspaceDir()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			fmt.Printf("Workspace %s does not exist, create a file first\n", dir)
+			return
+		}
+		fmt.Println("Workspace:", dir)
+
+		found := 0
+		err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+				return nil
+			}
+			found++
+			name, _ := filepath.Rel(dir, path)
+			name = filepath.ToSlash(name)
+			fileLines, err := readFileLines(path)
+			if err != nil {
+				fmt.Printf("%s (error: %v)\n", name, err)
+				return nil
+			}
+			requests := 0
+			for _, line := range fileLines {
+				if strings.TrimSpace(line) != "" {
+					requests++
+				}
+			}
+			fmt.Printf("%s (%d lines)\n", name, requests)
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Error reading workspace:", err)
+			return
+		}
+		if found == 0 {
+			fmt.Println("No files in workspace")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
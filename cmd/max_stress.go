@@ -7,15 +7,12 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 	"math"
-	"net/http/httptrace"
 	"github.com/spf13/cobra"
 )
 
@@ -50,9 +47,17 @@ var maxStressCmd = &cobra.Command{
             }
         }
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-            url = "http://" + url
-        }
+		url, err = normalizeURL(url)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		transport, err := newTransport(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating transport:", err)
+			return
+		}
 
 		incrementArray := [5]int{100, 50, 10, 5, 1}
 		increment := 0
@@ -74,7 +79,7 @@ var maxStressCmd = &cobra.Command{
 	
 			for i := 0; i < times; i++ {
 				wg.Add(1)
-				go maxStressRequest(url, &wg, ch)
+				go maxStressRequest(transport, url, &wg, ch)
 			}
 
 			go func () {
@@ -83,17 +88,7 @@ var maxStressCmd = &cobra.Command{
 			}()
 	
 			for response := range ch {
-				result.TotalDNSTimeRecorded += response.DNS
-				result.TotalConnectTimeRecorded += response.Connect
-				result.TotalTLSTimeRecorded += response.TLS
-				result.TotalTimeRecorded += response.TotalTime
-				result.Status[response.Status]++
-				if result.Fastest > response.TotalTime {
-					result.Fastest = response.TotalTime
-				}
-				if result.Slowest < response.TotalTime {
-					result.Slowest = response.TotalTime
-				}
+				result.add(response)
 			}
 
 			checkTime := time.Since(startTime)
@@ -121,8 +116,10 @@ var maxStressCmd = &cobra.Command{
 		fmt.Println("Method: 'GET'")
 		fmt.Println("Number of concurrent workers:", stressFlags.NumWorkers)
 		fmt.Println("Average DNS Runtime:", averageDNSTime)
-		fmt.Println("Average Connect Runtime:", averageConnectTime)
-		fmt.Println("Average TLS Runtime:", averageTLSTime)
+		if !transportFlags.HTTP3 {
+			fmt.Println("Average Connect Runtime:", averageConnectTime)
+			fmt.Println("Average TLS Runtime:", averageTLSTime)
+		}
 		fmt.Println("Average Total Runtime:", averageTime)
 		fmt.Println("Fastest Runtime: ", result.Fastest)
 		fmt.Println("Slowest Runtime: ", result.Slowest)
@@ -130,50 +127,24 @@ var maxStressCmd = &cobra.Command{
 		for status, count := range result.Status {
 			fmt.Printf("%s: %d\n", status, count)
 		}
+		printRecordBreakdown(result)
+		printConnectionStats()
 	},
 }
 
-func maxStressRequest(url string, wg *sync.WaitGroup, ch chan <- measuredResponse) {
+func maxStressRequest(transport http.RoundTripper, url string, wg *sync.WaitGroup, ch chan <- measuredResponse) {
 	defer wg.Done()
 	req, _ := http.NewRequest("GET", url, nil)
 
-	measured := measuredResponse{}
-	var start, connect, dns, tlsHandshake time.Time
-
-	trace := &httptrace.ClientTrace{
-		DNSStart: func(dsi httptrace.DNSStartInfo) { dns = time.Now() },
-		DNSDone: func(ddi httptrace.DNSDoneInfo) {
-			measured.DNS = time.Since(dns)
-		},
-		ConnectStart: func(network, addr string) { connect = time.Now() },
-		ConnectDone: func(network, addr string, err error) {
-			measured.Connect = time.Since(connect)
-		},
-		TLSHandshakeStart: func() { tlsHandshake = time.Now() },
-		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
-			measured.TLS = time.Since(tlsHandshake)
-		},
-
-		// From when the first byte is registered back
-		GotFirstResponseByte: func() {
-			measured.TotalTime = time.Since(start)
-		},
-	}
-
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-	start = time.Now()
-
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	measured, err := measureRequest(transport.RoundTrip, req)
 	if err != nil {
 		return
 	}
-	measured.Start = start
-	measured.Res = resp
-	measured.Status = resp.Status
-	defer resp.Body.Close()
+	resp := measured.Res
+	drainAndClose(resp.Body)
 	
 	if stressFlags.ShowSingleProcesses {
-		fmt.Printf("Status: %s\nTotal Time: %v\n\n", resp.Status, measured.TotalTime)
+		fmt.Printf("Status: %s\nTotal Time: %v\nBody Size: %s\n\n", resp.Status, measured.TotalTime, bodySummary(measured))
 	}
 	ch <- measured
 }
\ No newline at end of file
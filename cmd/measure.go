@@ -0,0 +1,112 @@
+// cmd/measure.go
+//
+// Every timed request goes through measureRequest so the httptrace
+// hooks (and the span recording that hangs off them) only live in
+// one place.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// doFunc sends a request, e.g. client.Do or transport.RoundTrip.
+type doFunc func(*http.Request) (*http.Response, error)
+
+type measuredKey struct{}
+
+// measuredFromContext returns the response being timed for the request
+// that owns ctx, for phases httptrace has no hooks for. It returns nil
+// outside measureRequest.
+func measuredFromContext(ctx context.Context) *measuredResponse {
+	measured, _ := ctx.Value(measuredKey{}).(*measuredResponse)
+	return measured
+}
+
+func measureRequest(do doFunc, req *http.Request) (measuredResponse, error) {
+	measured := measuredResponse{}
+	sp := startSpan(req)
+	var start, connect, dns, tlsHandshake time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(dsi httptrace.DNSStartInfo) {
+			dns = time.Now()
+			sp.event("dns.start")
+		},
+		DNSDone: func(ddi httptrace.DNSDoneInfo) {
+			measured.DNS = time.Since(dns)
+			sp.event("dns.done")
+		},
+		ConnectStart: func(network, addr string) {
+			connect = time.Now()
+			sp.event("connect.start")
+		},
+		ConnectDone: func(network, addr string, err error) {
+			measured.Connect = time.Since(connect)
+			sp.event("connect.done")
+		},
+		TLSHandshakeStart: func() {
+			tlsHandshake = time.Now()
+			sp.event("tls.start")
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			measured.TLS = time.Since(tlsHandshake)
+			if err == nil {
+				recordTLS(&measured, cs)
+			}
+			sp.event("tls.done")
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			measured.Reused = info.Reused
+			sp.event("got_conn")
+		},
+
+		// From when the first byte is registered back
+		GotFirstResponseByte: func() {
+			measured.TotalTime = time.Since(start)
+			sp.event("first_byte")
+		},
+	}
+
+	ctx := context.WithValue(req.Context(), measuredKey{}, &measured)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+	start = time.Now()
+
+	resp, err := do(req)
+	if err != nil {
+		sp.finish(nil, err)
+		return measured, err
+	}
+	if measured.TotalTime == 0 {
+		// Transports without httptrace support (HTTP/3) never call
+		// GotFirstResponseByte; headers arriving is the closest point.
+		measured.TotalTime = time.Since(start)
+	}
+	measured.Start = start
+	measured.Res = resp
+	measured.Status = resp.Status
+	measured.Proto = resp.Proto
+	if measured.TLSVersion == "" && resp.TLS != nil {
+		// Reused connections and HTTP/3 skip TLSHandshakeDone.
+		recordTLS(&measured, *resp.TLS)
+	}
+	if resp.ProtoMajor == 3 {
+		// HTTP/3 has no GotConn hook; only the request that dialed
+		// has a handshake time.
+		measured.Reused = measured.QUIC == 0
+	}
+	sp.attachTo(resp)
+	return measured, nil
+}
+
+// drainAndClose reads what's left of body before closing it, so the
+// connection can go back to the pool instead of being torn down.
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(io.Discard, body)
+	body.Close()
+}
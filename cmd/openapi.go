@@ -0,0 +1,604 @@
+// cmd/openapi.go
+//
+// OpenAPI 3 support: 'openapi generate' writes a suite with a sample
+// request for every operation in a spec, and --validate-against checks
+// each response of execute and stress against the spec: the status code
+// must be documented, required headers present, the Content-Type one of
+// the documented ones and JSON bodies valid against their schema.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var contractFlags struct {
+	Spec string
+}
+
+// contract is the spec responses are checked against, nil if none.
+var contract *openAPISpec
+
+func init() {
+	for _, cmd := range []*cobra.Command{executeCmd, stressCmd} {
+		cmd.Flags().StringVar(&contractFlags.Spec, "validate-against", "", "OpenAPI 3 spec to check every response against")
+	}
+	openAPICmd.AddCommand(openAPIGenerateCmd)
+	rootCmd.AddCommand(openAPICmd)
+}
+
+// loadContract loads the --validate-against spec, if given.
+func loadContract() error {
+	if contractFlags.Spec == "" {
+		return nil
+	}
+	spec, err := loadOpenAPI(contractFlags.Spec)
+	if err != nil {
+		return err
+	}
+	contract = spec
+	return nil
+}
+
+type openAPISpec struct {
+	doc       *schemaDoc
+	validator *schemaValidator
+	serverURL string
+	basePath  string
+	routes    []openAPIRoute
+}
+
+type openAPIRoute struct {
+	template string
+	segments []string
+	literals int
+	item     map[string]any
+	doc      *schemaDoc
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func loadOpenAPI(path string) (*openAPISpec, error) {
+	validator := newSchemaValidator()
+	doc, err := validator.loadDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	root, _ := doc.root.(map[string]any)
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 spec", path)
+	}
+	spec := &openAPISpec{doc: doc, validator: validator}
+
+	if servers := schemaList(root["servers"]); len(servers) > 0 {
+		server, _ := servers[0].(map[string]any)
+		raw, _ := server["url"].(string)
+		variables, _ := server["variables"].(map[string]any)
+		for name, variable := range variables {
+			variable, _ := variable.(map[string]any)
+			raw = strings.ReplaceAll(raw, "{"+name+"}", fmt.Sprint(variable["default"]))
+		}
+		spec.serverURL = strings.TrimSuffix(raw, "/")
+		if u, err := url.Parse(spec.serverURL); err == nil {
+			spec.basePath = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	paths, _ := root["paths"].(map[string]any)
+	for template, item := range paths {
+		itemMap, itemDoc := spec.deref(doc, item)
+		if itemMap == nil {
+			continue
+		}
+		route := openAPIRoute{template: template, segments: strings.Split(strings.Trim(template, "/"), "/"), item: itemMap, doc: itemDoc}
+		for _, segment := range route.segments {
+			if !strings.Contains(segment, "{") {
+				route.literals++
+			}
+		}
+		spec.routes = append(spec.routes, route)
+	}
+	sort.Slice(spec.routes, func(i, j int) bool { return spec.routes[i].template < spec.routes[j].template })
+	return spec, nil
+}
+
+// deref follows $ref for the non-schema objects (path items, responses,
+// parameters, headers, request bodies) that may be references.
+func (s *openAPISpec) deref(doc *schemaDoc, node any) (map[string]any, *schemaDoc) {
+	for i := 0; i < 32; i++ {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, doc
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object, doc
+		}
+		target, targetDoc, err := s.validator.resolveRef(doc, ref)
+		if err != nil {
+			return nil, doc
+		}
+		node, doc = target, targetDoc
+	}
+	return nil, doc
+}
+
+// operation finds the operation for a request on the path template with
+// the most literal segments. A method that template doesn't document is
+// reported as such rather than matched against a less specific one, so
+// op is nil when ok is true but the method is missing.
+func (s *openAPISpec) operation(method, path string) (op map[string]any, route openAPIRoute, ok bool) {
+	if s.basePath != "" {
+		if !strings.HasPrefix(path, s.basePath) {
+			return nil, openAPIRoute{}, false
+		}
+		path = strings.TrimPrefix(path, s.basePath)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, candidate := range s.routes {
+		if len(candidate.segments) != len(segments) || (ok && candidate.literals < route.literals) {
+			continue
+		}
+		matched := true
+		for i, segment := range candidate.segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				if segments[i] == "" {
+					matched = false
+					break
+				}
+			} else if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		candidateOp, _ := candidate.item[strings.ToLower(method)].(map[string]any)
+		// Equally specific templates: the one with the method wins.
+		if !ok || candidate.literals > route.literals || op == nil && candidateOp != nil {
+			op, route, ok = candidateOp, candidate, true
+		}
+	}
+	return op, route, ok
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// check validates a response to req and returns the violations found.
+func (s *openAPISpec) check(req *http.Request, resp *http.Response, body []byte) []string {
+	op, route, ok := s.operation(req.Method, req.URL.Path)
+	if !ok {
+		return []string{fmt.Sprintf("%s %s: no operation in the spec", req.Method, req.URL.Path)}
+	}
+	if op == nil {
+		return []string{fmt.Sprintf("%s %s: method not documented", req.Method, route.template)}
+	}
+	name := req.Method + " " + route.template
+
+	responses, doc := s.deref(route.doc, op["responses"])
+	code := strconv.Itoa(resp.StatusCode)
+	var response any
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if r, ok := responses[key]; ok {
+			response = r
+			break
+		}
+	}
+	if response == nil {
+		return []string{fmt.Sprintf("%s: status %s is not documented", name, code)}
+	}
+	responseMap, doc := s.deref(doc, response)
+	name += " " + code
+
+	var violations []string
+	headers, _ := responseMap["headers"].(map[string]any)
+	headerNames := make([]string, 0, len(headers))
+	for header := range headers {
+		headerNames = append(headerNames, header)
+	}
+	sort.Strings(headerNames)
+	for _, header := range headerNames {
+		if strings.EqualFold(header, "Content-Type") {
+			continue
+		}
+		h, _ := s.deref(doc, headers[header])
+		if h["required"] == true && resp.Header.Get(header) == "" {
+			violations = append(violations, fmt.Sprintf("%s: missing required header %s", name, header))
+		}
+	}
+
+	content, _ := responseMap["content"].(map[string]any)
+	if len(content) == 0 {
+		return violations
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var media any
+	for _, key := range []string{mediaType, strings.Split(mediaType, "/")[0] + "/*", "*/*"} {
+		if m, ok := content[key]; ok {
+			media = m
+			break
+		}
+	}
+	if media == nil {
+		documented := make([]string, 0, len(content))
+		for key := range content {
+			documented = append(documented, key)
+		}
+		sort.Strings(documented)
+		return append(violations, fmt.Sprintf("%s: Content-Type %q is not documented (expected %s)", name, mediaType, strings.Join(documented, ", ")))
+	}
+	mediaMap, _ := media.(map[string]any)
+	schema, ok := mediaMap["schema"]
+	if !ok || !isJSONMediaType(mediaType) {
+		return violations
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return append(violations, fmt.Sprintf("%s: body is not valid JSON: %v", name, err))
+	}
+	for _, violation := range s.validator.validate(doc, schema, value) {
+		violations = append(violations, name+": "+violation.String())
+	}
+	return violations
+}
+
+// sampleValue builds an example value for schema, preferring the
+// examples and defaults the spec gives.
+func (s *openAPISpec) sampleValue(doc *schemaDoc, schema any, depth int) any {
+	object, ok := schema.(map[string]any)
+	if !ok || depth > 8 {
+		return nil
+	}
+	if ref, ok := object["$ref"].(string); ok {
+		target, targetDoc, err := s.validator.resolveRef(doc, ref)
+		if err != nil {
+			return nil
+		}
+		return s.sampleValue(targetDoc, target, depth+1)
+	}
+	for _, key := range []string{"example", "default", "const"} {
+		if value, ok := object[key]; ok {
+			return value
+		}
+	}
+	if examples := schemaList(object["examples"]); len(examples) > 0 {
+		return examples[0]
+	}
+	if enum := schemaList(object["enum"]); len(enum) > 0 {
+		return enum[0]
+	}
+	if all := schemaList(object["allOf"]); len(all) > 0 {
+		merged := make(map[string]any)
+		for _, sub := range all {
+			if part, ok := s.sampleValue(doc, sub, depth+1).(map[string]any); ok {
+				for key, value := range part {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if branches := schemaList(object[key]); len(branches) > 0 {
+			return s.sampleValue(doc, branches[0], depth+1)
+		}
+	}
+
+	kind := ""
+	for _, t := range schemaTypes(object["type"]) {
+		if t != "null" {
+			kind = t
+			break
+		}
+	}
+	if kind == "" {
+		if _, ok := object["properties"]; ok {
+			kind = "object"
+		} else if _, ok := object["items"]; ok {
+			kind = "array"
+		}
+	}
+	switch kind {
+	case "object":
+		sample := make(map[string]any)
+		properties, _ := object["properties"].(map[string]any)
+		for name, property := range properties {
+			if p, ok := property.(map[string]any); ok && p["readOnly"] == true {
+				continue
+			}
+			sample[name] = s.sampleValue(doc, property, depth+1)
+		}
+		return sample
+	case "array":
+		if items, ok := object["items"]; ok {
+			return []any{s.sampleValue(doc, items, depth+1)}
+		}
+		return []any{}
+	case "string":
+		format, _ := object["format"].(string)
+		sample := map[string]string{
+			"date-time": "2024-01-01T00:00:00Z",
+			"date":      "2024-01-01",
+			"time":      "00:00:00Z",
+			"email":     "user@example.com",
+			"uuid":      "00000000-0000-4000-8000-000000000000",
+			"uri":       "https://example.com",
+			"url":       "https://example.com",
+			"ipv4":      "192.0.2.1",
+			"ipv6":      "2001:db8::1",
+		}[format]
+		if sample == "" {
+			sample = "string"
+		}
+		if n, ok := toFloat(object["minLength"]); ok && float64(len(sample)) < n {
+			sample += strings.Repeat("x", int(n)-len(sample))
+		}
+		return sample
+	case "integer", "number":
+		if n, ok := toFloat(object["minimum"]); ok {
+			return n
+		}
+		if n, ok := toFloat(object["maximum"]); ok && n < 1 {
+			return n
+		}
+		return 1.0
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// formatParameter renders a sample as a path, query or header value.
+func formatParameter(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []any:
+		parts := make([]string, len(value))
+		for i, item := range value {
+			parts[i] = formatParameter(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+// parameterSample picks a parameter's example, or one from its schema.
+func (s *openAPISpec) parameterSample(doc *schemaDoc, parameter map[string]any) string {
+	if example, ok := parameter["example"]; ok {
+		return formatParameter(example)
+	}
+	if examples, ok := parameter["examples"].(map[string]any); ok && len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for name := range examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		example, _ := s.deref(doc, examples[names[0]])
+		return formatParameter(example["value"])
+	}
+	return formatParameter(s.sampleValue(doc, parameter["schema"], 0))
+}
+
+// generate builds one suite line per operation.
+func (s *openAPISpec) generate() importResult {
+	var result importResult
+	root, _ := s.doc.root.(map[string]any)
+	if s.serverURL == "" || strings.HasPrefix(s.serverURL, "/") {
+		result.note("the spec has no absolute server URL, run the suite with a profile whose base_url points at the service")
+	}
+	globalSecurity := schemaList(root["security"])
+
+	for _, route := range s.routes {
+		for _, method := range openAPIMethods {
+			op, ok := route.item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			name := strings.ToUpper(method) + " " + route.template
+			line := lines{Method: strings.ToUpper(method), NumTimes: 1}
+
+			// Operation parameters override path item ones of the same name.
+			parameters := make(map[string]map[string]any)
+			var order []string
+			for _, list := range []any{route.item["parameters"], op["parameters"]} {
+				for _, p := range schemaList(list) {
+					parameter, _ := s.deref(route.doc, p)
+					if parameter == nil {
+						continue
+					}
+					key := fmt.Sprint(parameter["in"], ":", parameter["name"])
+					if _, seen := parameters[key]; !seen {
+						order = append(order, key)
+					}
+					parameters[key] = parameter
+				}
+			}
+
+			path := route.template
+			query := url.Values{}
+			for _, key := range order {
+				parameter := parameters[key]
+				paramName, _ := parameter["name"].(string)
+				required := parameter["required"] == true
+				_, hasExample := parameter["example"]
+				switch parameter["in"] {
+				case "path":
+					path = strings.ReplaceAll(path, "{"+paramName+"}", url.PathEscape(s.parameterSample(route.doc, parameter)))
+				case "query":
+					if required || hasExample {
+						query.Set(paramName, s.parameterSample(route.doc, parameter))
+					}
+				case "header":
+					if required {
+						line.Headers = append(line.Headers, paramName+": "+s.parameterSample(route.doc, parameter))
+					}
+				case "cookie":
+					if required {
+						result.note("%s: cookie parameter %s", name, paramName)
+					}
+				}
+			}
+			line.URL = s.serverURL + path
+			if line.URL == "" {
+				line.URL = "/"
+			}
+			if len(query) > 0 {
+				line.URL += "?" + query.Encode()
+			}
+
+			if requestBody, doc := s.deref(route.doc, op["requestBody"]); requestBody != nil {
+				s.sampleBody(doc, requestBody, name, &line, &result)
+			}
+
+			security := globalSecurity
+			if opSecurity, ok := op["security"]; ok {
+				security = schemaList(opSecurity)
+			}
+			if schemes := securitySchemes(security); len(schemes) > 0 {
+				result.note("%s: needs %s auth, pass it with --bearer, --basic, --header or a profile", name, strings.Join(schemes, " or "))
+			}
+			result.lines = append(result.lines, line)
+		}
+	}
+	return result
+}
+
+func securitySchemes(security []any) []string {
+	var schemes []string
+	for _, requirement := range security {
+		requirement, _ := requirement.(map[string]any)
+		if len(requirement) == 0 {
+			// An empty requirement makes auth optional.
+			return nil
+		}
+		for scheme := range requirement {
+			if !containsString(schemes, scheme) {
+				schemes = append(schemes, scheme)
+			}
+		}
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// sampleBody fills in the request body from the first media type hpgo
+// can send, preferring JSON.
+func (s *openAPISpec) sampleBody(doc *schemaDoc, requestBody map[string]any, name string, line *lines, result *importResult) {
+	content, _ := requestBody["content"].(map[string]any)
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Slice(mediaTypes, func(i, j int) bool {
+		rank := func(t string) int {
+			switch {
+			case isJSONMediaType(t):
+				return 0
+			case t == "application/x-www-form-urlencoded":
+				return 1
+			case strings.HasPrefix(t, "text/"):
+				return 2
+			}
+			return 3
+		}
+		return rank(mediaTypes[i]) < rank(mediaTypes[j])
+	})
+	if len(mediaTypes) == 0 {
+		return
+	}
+	mediaType := mediaTypes[0]
+	media, _ := content[mediaType].(map[string]any)
+
+	var sample any
+	if example, ok := media["example"]; ok {
+		sample = example
+	} else if examples, ok := media["examples"].(map[string]any); ok && len(examples) > 0 {
+		names := make([]string, 0, len(examples))
+		for n := range examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		example, _ := s.deref(doc, examples[names[0]])
+		sample = example["value"]
+	} else {
+		sample = s.sampleValue(doc, media["schema"], 0)
+	}
+
+	switch {
+	case isJSONMediaType(mediaType):
+		data, err := json.Marshal(sample)
+		if err != nil {
+			result.note("%s: can't encode the sample body: %v", name, err)
+			return
+		}
+		line.Body = string(data)
+	case mediaType == "application/x-www-form-urlencoded":
+		form := url.Values{}
+		if fields, ok := sample.(map[string]any); ok {
+			for key, value := range fields {
+				form.Set(key, formatParameter(value))
+			}
+		}
+		line.Body = form.Encode()
+	case strings.HasPrefix(mediaType, "text/"):
+		line.Body = formatParameter(sample)
+	default:
+		result.note("%s: %s request body", name, mediaType)
+		return
+	}
+	line.Headers = append(line.Headers, "Content-Type: "+mediaType)
+}
+
+var openAPICmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Generates suites from OpenAPI 3 specs",
+}
+
+var openAPIGenerateCmd = &cobra.Command{
+	Use:   "generate [spec] [fileName]",
+	Short: "Writes a sample request for every operation in a spec",
+	Long: "Writes a sample request for every operation in an OpenAPI 3 spec, " +
+		"appending to the suite if a file name is given and printing the lines otherwise. " +
+		"Samples come from the spec's examples and defaults, or are built from the schemas.",
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, err := loadOpenAPI(args[0])
+		if err != nil {
+			fmt.Println("Error reading spec:", err)
+			exit(1)
+		}
+		result := spec.generate()
+		if len(args) == 2 {
+			writeImport(args[0], args[1], result)
+			return
+		}
+		for _, line := range result.lines {
+			fmt.Println(formatLine(line))
+		}
+		for _, note := range result.notes {
+			fmt.Fprintln(os.Stderr, "note:", note)
+		}
+	},
+}
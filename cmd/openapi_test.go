@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPISpec = `
+openapi: 3.0.3
+servers:
+  - url: https://{host}/v1
+    variables:
+      host: {default: api.example.com}
+security:
+  - bearer: []
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer, minimum: 10}}
+        - {name: tag, in: query, example: cat}
+        - {name: X-Trace, in: header, required: true, schema: {type: string, format: uuid}}
+      responses:
+        "200":
+          description: pets
+          headers:
+            X-Total: {required: true, schema: {type: integer}}
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: "#/components/schemas/Pet"}}
+    post:
+      security: [{}]
+      requestBody:
+        content:
+          text/plain: {schema: {type: string}}
+          application/json: {schema: {$ref: "#/components/schemas/Pet"}}
+      responses:
+        "201": {$ref: "#/components/responses/Created"}
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}, example: 7}
+    get:
+      responses:
+        2XX: {description: pet}
+    delete:
+      parameters:
+        - {name: id, in: path, required: true, example: 8}
+        - {name: session, in: cookie, required: true}
+      responses:
+        default: {description: deleted}
+  /pets/mine:
+    get:
+      responses:
+        "200": {description: my pets}
+  /upload:
+    put:
+      requestBody:
+        content:
+          application/octet-stream: {}
+      responses:
+        "204": {description: stored}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, example: Rex}
+  responses:
+    Created:
+      description: created
+      content:
+        application/json:
+          schema: {$ref: "#/components/schemas/Pet"}
+`
+
+func loadTestOpenAPI(t *testing.T) *openAPISpec {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	os.WriteFile(path, []byte(testOpenAPISpec), 0644)
+	spec, err := loadOpenAPI(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestOpenAPIGenerate(t *testing.T) {
+	result := loadTestOpenAPI(t).generate()
+	var got []string
+	for _, line := range result.lines {
+		got = append(got, formatLine(line))
+	}
+	want := []string{
+		`https://api.example.com/v1/pets?tag=cat 1 "header=X-Trace: 00000000-0000-4000-8000-000000000000"`,
+		`https://api.example.com/v1/pets 1 method=POST "header=Content-Type: application/json" "body={\"name\":\"Rex\"}"`,
+		`https://api.example.com/v1/pets/mine 1`,
+		`https://api.example.com/v1/pets/7 1`,
+		`https://api.example.com/v1/pets/8 1 method=DELETE`,
+		`https://api.example.com/v1/upload 1 method=PUT`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	wantNotes := []string{
+		"GET /pets: needs bearer auth, pass it with --bearer, --basic, --header or a profile",
+		"GET /pets/mine: needs bearer auth, pass it with --bearer, --basic, --header or a profile",
+		"GET /pets/{id}: needs bearer auth, pass it with --bearer, --basic, --header or a profile",
+		"DELETE /pets/{id}: cookie parameter session",
+		"DELETE /pets/{id}: needs bearer auth, pass it with --bearer, --basic, --header or a profile",
+		"PUT /upload: application/octet-stream request body",
+		"PUT /upload: needs bearer auth, pass it with --bearer, --basic, --header or a profile",
+	}
+	if strings.Join(result.notes, "\n") != strings.Join(wantNotes, "\n") {
+		t.Errorf("notes:\n%s\nwant:\n%s", strings.Join(result.notes, "\n"), strings.Join(wantNotes, "\n"))
+	}
+}
+
+func TestOpenAPIOperation(t *testing.T) {
+	spec := loadTestOpenAPI(t)
+	tests := []struct {
+		method, path string
+		template     string
+		documented   bool
+	}{
+		{"GET", "/v1/pets", "/pets", true},
+		{"GET", "/v1/pets/", "/pets", true},
+		{"GET", "/v1/pets/mine", "/pets/mine", true},
+		{"GET", "/v1/pets/7", "/pets/{id}", true},
+		{"DELETE", "/v1/pets/7", "/pets/{id}", true},
+		// /pets/mine is the closer match, even though only /pets/{id}
+		// documents DELETE.
+		{"DELETE", "/v1/pets/mine", "/pets/mine", false},
+		{"PATCH", "/v1/pets", "/pets", false},
+		{"GET", "/v1/pets/7/toys", "", false},
+		{"GET", "/pets", "", false},
+	}
+	for _, tt := range tests {
+		op, route, ok := spec.operation(tt.method, tt.path)
+		if ok != (tt.template != "") || route.template != tt.template || (op != nil) != tt.documented {
+			t.Errorf("%s %s: got %s, %v, documented %v, want %s, documented %v",
+				tt.method, tt.path, route.template, ok, op != nil, tt.template, tt.documented)
+		}
+	}
+}
+
+func TestOpenAPICheck(t *testing.T) {
+	spec := loadTestOpenAPI(t)
+	tests := []struct {
+		method, path string
+		status       int
+		headers      map[string]string
+		body         string
+		want         []string
+	}{
+		{"GET", "/v1/pets", 200, map[string]string{"X-Total": "1", "Content-Type": "application/json"}, `[{"name": "Rex"}]`, nil},
+		{"GET", "/v1/pets", 200, map[string]string{"Content-Type": "application/json; charset=utf-8"}, `[{}]`,
+			[]string{"GET /pets 200: missing required header X-Total", `GET /pets 200: #/0: missing required property "name"`}},
+		{"GET", "/v1/pets", 200, map[string]string{"X-Total": "1", "Content-Type": "text/html"}, `<p>`,
+			[]string{`GET /pets 200: Content-Type "text/html" is not documented (expected application/json)`}},
+		{"GET", "/v1/pets", 200, map[string]string{"X-Total": "1", "Content-Type": "application/json"}, `[`,
+			[]string{"GET /pets 200: body is not valid JSON: unexpected end of JSON input"}},
+		{"GET", "/v1/pets", 404, nil, "", []string{"GET /pets: status 404 is not documented"}},
+		{"POST", "/v1/pets", 201, map[string]string{"Content-Type": "application/json"}, `{"name": 1}`,
+			[]string{"POST /pets 201: #/name: expected string, got integer"}},
+		{"GET", "/v1/pets/7", 204, nil, "", nil},
+		{"DELETE", "/v1/pets/7", 500, nil, "", nil},
+		{"DELETE", "/v1/pets/mine", 200, nil, "", []string{"DELETE /pets/mine: method not documented"}},
+		{"GET", "/v1/toys", 200, nil, "", []string{"GET /v1/toys: no operation in the spec"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "https://api.example.com"+tt.path, nil)
+		resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+		for key, value := range tt.headers {
+			resp.Header.Set(key, value)
+		}
+		got := spec.check(req, resp, []byte(tt.body))
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s %s %d: got %q, want %q", tt.method, tt.path, tt.status, got, tt.want)
+		}
+	}
+}
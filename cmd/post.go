@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"net/http"
 	"encoding/json"
 	"strings"
@@ -11,6 +11,7 @@ import (
 )
 
 func init() {
+	addRenderFlags(postCmd)
 	rootCmd.AddCommand(postCmd)
 }
 
@@ -19,11 +20,12 @@ var postCmd = &cobra.Command{
 	Short: "Sends POST requests to a URL",
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		url := args[0]
 		data := args[1]
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			url = "http://" + url
+		url, err := normalizeURL(args[0])
+		if err != nil {
+			fmt.Println(err)
+			return
 		}
 
 		valid := json.Valid([]byte(data))
@@ -34,19 +36,40 @@ var postCmd = &cobra.Command{
 
 		payload := strings.NewReader(data)
 
-		resp, err := http.Post(url, "application/json", payload)
+		req, err := http.NewRequest("POST", url, payload)
 		if err != nil {
 			panic(err)
 		}
-		defer resp.Body.Close()
+		req.Header.Set("Content-Type", "application/json")
+
+		if err := validateRenderFlags(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		schema, err := commandSchema()
+		if err != nil {
+			fmt.Println("Error reading schema:", err)
+			return
+		}
 
-		fmt.Println("Response status:", resp.Status)
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			fmt.Println(scanner.Text())
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
 		}
-		if err := scanner.Err(); err != nil {
+
+		measured, err := measureRequest(client.Do, req)
+		if err != nil {
+			panic(err)
+		}
+		resp := measured.Res
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
 			panic(err)
 		}
+		printResponse(measured, body)
+		reportSchema(schema, body)
 	},
 }
@@ -0,0 +1,268 @@
+// cmd/record.go
+//
+// 'record' is a reverse proxy that writes every request passing through
+// it to a suite, with at= set to when it arrived, so 'execute' can replay
+// real client traffic with its original timing (or scaled by --speed).
+// Lines are appended as requests finish, so nothing is lost if the
+// recorder is killed.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+var recordFlags struct {
+	Listen         string
+	Target         string
+	ExcludeHeaders []string
+	IncludeHeaders []string
+}
+
+func init() {
+	recordCmd.Flags().StringVar(&recordFlags.Listen, "listen", "127.0.0.1:8080", "Address to listen on")
+	recordCmd.Flags().StringVar(&recordFlags.Target, "target", "", "URL of the service to forward requests to")
+	recordCmd.Flags().StringArrayVar(&recordFlags.ExcludeHeaders, "exclude-header", nil, "Header to leave out of the suite, e.g. X-Api-Key (repeatable)")
+	recordCmd.Flags().StringArrayVar(&recordFlags.IncludeHeaders, "include-header", nil, "Credential header to record anyway: Authorization, Cookie or Proxy-Authorization (repeatable)")
+	recordCmd.MarkFlagRequired("target")
+	rootCmd.AddCommand(recordCmd)
+}
+
+// Headers the transport sets itself, or that only make sense for one
+// connection.
+var unrecordedHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// Credentials are left out of the suite unless --include-header asks for
+// them.
+var credentialHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+type recorder struct {
+	mu       sync.Mutex
+	file     *os.File
+	target   *url.URL
+	excluded map[string]bool
+	first    time.Time
+	count    int
+}
+
+// suiteBody returns body as it can be written on a suite line. JSON is
+// compacted; binary bodies can't be written.
+func suiteBody(body []byte) (string, bool) {
+	if json.Valid(body) {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err == nil {
+			body = compact.Bytes()
+		}
+	}
+	if !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0 {
+		return "", false
+	}
+	return string(body), true
+}
+
+// targetURL is where the proxy sends r, the way ProxyRequest.SetURL
+// builds it.
+func (rec *recorder) targetURL(r *http.Request) string {
+	u := *rec.target
+	u.Path = strings.TrimSuffix(rec.target.Path, "/") + r.URL.Path
+	u.RawPath = ""
+	switch {
+	case rec.target.RawQuery == "":
+		u.RawQuery = r.URL.RawQuery
+	case r.URL.RawQuery != "":
+		u.RawQuery = rec.target.RawQuery + "&" + r.URL.RawQuery
+	}
+	return u.String()
+}
+
+// add writes one request to the suite. arrived is when the request
+// came in, its at= is the offset from the first arrival.
+func (rec *recorder) add(r *http.Request, body []byte, arrived time.Time, status int, elapsed time.Duration) {
+	line := lines{URL: rec.targetURL(r), NumTimes: 1, Method: r.Method}
+
+	keys := make([]string, 0, len(r.Header))
+	for key := range r.Header {
+		if !unrecordedHeaders[key] && !rec.excluded[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range r.Header[key] {
+			line.Headers = append(line.Headers, key+": "+value)
+		}
+	}
+
+	note := ""
+	if len(body) > 0 {
+		if text, ok := suiteBody(body); ok {
+			line.Body = text
+		} else {
+			note = fmt.Sprintf(" (%s binary body not recorded)", formatBytes(int64(len(body))))
+		}
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	line.At = arrived.Sub(rec.first).Round(time.Millisecond)
+	text := formatLine(line)
+	if envPattern.MatchString(text) || secretPattern.MatchString(text) {
+		// The suite format has no way to keep these from being
+		// interpolated, so execute would send something else.
+		note += " (not recorded: contains ${...} or {{...}}, which execute would interpolate)"
+	} else if _, err := rec.file.WriteString(text + "\n"); err != nil {
+		fmt.Println("Error writing to file:", err)
+	} else {
+		rec.count++
+	}
+
+	outputMu.Lock()
+	fmt.Printf("%v %s %s %d %v%s\n", line.At, r.Method, r.URL.RequestURI(), status, elapsed.Round(time.Microsecond), note)
+	outputMu.Unlock()
+}
+
+// handler records each request, then hands it to proxy.
+func (rec *recorder) handler(proxy http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived := time.Now()
+		rec.mu.Lock()
+		if rec.first.IsZero() {
+			rec.first = arrived
+		}
+		rec.mu.Unlock()
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		proxy.ServeHTTP(sw, r)
+		rec.add(r, body, arrived, sw.status, time.Since(arrived))
+	})
+}
+
+var recordCmd = &cobra.Command{
+	Use:   "record [fileName]",
+	Short: "Records the requests passing through a reverse proxy into a suite",
+	Long: "Forwards every request on --listen to --target and appends it to the suite, " +
+		"with at= set to when it arrived so 'execute' replays the original timing " +
+		"(use execute --speed to scale it). Headers are recorded as sent except " +
+		"Authorization, Cookie and Proxy-Authorization, which need --include-header; " +
+		"use --exclude-header to leave out others. Requests containing ${...} or {{...}} " +
+		"are forwarded but not recorded, since execute would interpolate them.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		target, err := url.Parse(recordFlags.Target)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			fmt.Println("--target must be an http:// or https:// URL")
+			exit(1)
+		}
+		filePath, err := suitePath(args[0])
+		if err != nil {
+			fmt.Println(err)
+			exit(1)
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			fmt.Println("Error creating directory:", err)
+			exit(1)
+		}
+		file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Println("Error opening file:", err)
+			exit(1)
+		}
+		defer file.Close()
+		if info, err := file.Stat(); err == nil && info.Size() > 0 {
+			// at= offsets restart with this recording.
+			fmt.Println("Appending to", filePath, "- earlier lines keep their own at= timings")
+		}
+
+		// The proxy forwards exactly what the client sent, so it only
+		// uses the protocol layer: no redirects, default headers or
+		// decompression of its own.
+		transport, err := newBaseTransport(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating transport:", err)
+			exit(1)
+		}
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+			},
+			Transport: transport,
+		}
+
+		excluded := make(map[string]bool)
+		for _, header := range credentialHeaders {
+			excluded[header] = true
+		}
+		for _, header := range recordFlags.ExcludeHeaders {
+			excluded[http.CanonicalHeaderKey(header)] = true
+		}
+		for _, header := range recordFlags.IncludeHeaders {
+			delete(excluded, http.CanonicalHeaderKey(header))
+		}
+		rec := &recorder{file: file, target: target, excluded: excluded}
+
+		listener, err := net.Listen("tcp", recordFlags.Listen)
+		if err != nil {
+			fmt.Println("Error listening:", err)
+			exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		server := &http.Server{Handler: rec.handler(proxy)}
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		fmt.Printf("Recording http://%s -> %s into %s\n", listener.Addr(), target, filePath)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("Error serving:", err)
+			exit(1)
+		}
+		<-closed
+		rec.mu.Lock()
+		fmt.Printf("\nRecorded %d requests into %s\n", rec.count, filePath)
+		rec.mu.Unlock()
+	},
+}
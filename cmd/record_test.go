@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	t.Setenv("HPGO_TEST_NAME", "hpgo-test-name")
+	t.Setenv("HPGO_TEST_PATH", "/tmp")
+
+	tests := []struct {
+		name    string
+		include []string
+		header  http.Header
+		body    string
+		want    lines
+		skipped bool
+	}{
+		{"credentials left out",
+			nil,
+			http.Header{"Authorization": {"Bearer t"}, "Cookie": {"a=1"}, "Proxy-Authorization": {"Basic x"}, "X-Api-Key": {"k"}, "X-Trace": {"1"}},
+			"",
+			lines{Headers: []string{"X-Trace: 1"}},
+			false},
+		{"credentials included",
+			[]string{"authorization"},
+			http.Header{"Authorization": {"Bearer t"}, "Cookie": {"a=1"}},
+			"",
+			lines{Headers: []string{"Authorization: Bearer t"}},
+			false},
+		{"multi-line body",
+			nil,
+			http.Header{"Content-Type": {"text/plain"}},
+			"line 1\nline 2\r\n",
+			lines{Headers: []string{"Content-Type: text/plain"}, Body: "line 1\nline 2\r\n"},
+			false},
+		{"JSON body compacted",
+			nil,
+			nil,
+			"{\n  \"a\": [1, 2]\n}",
+			lines{Body: `{"a":[1,2]}`},
+			false},
+		{"template in body",
+			nil,
+			nil,
+			`Hello {{secret "HPGO_TEST_NAME"}} in ${HPGO_TEST_PATH}`,
+			lines{},
+			true},
+		{"template in header",
+			nil,
+			http.Header{"X-Trace": {"${HPGO_TEST_PATH}"}},
+			"",
+			lines{},
+			true},
+	}
+	for _, tt := range tests {
+		file, err := os.Create(filepath.Join(t.TempDir(), "suite.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		excluded := map[string]bool{}
+		for _, header := range credentialHeaders {
+			excluded[header] = true
+		}
+		excluded["X-Api-Key"] = true
+		for _, header := range tt.include {
+			delete(excluded, http.CanonicalHeaderKey(header))
+		}
+		rec := &recorder{file: file, target: targetURL, excluded: excluded}
+		proxy := &httputil.ReverseProxy{Rewrite: func(pr *httputil.ProxyRequest) { pr.SetURL(targetURL) }}
+		server := httptest.NewServer(rec.handler(proxy))
+
+		req, _ := http.NewRequest("POST", server.URL+"/x?q=1", strings.NewReader(tt.body))
+		for key, values := range tt.header {
+			req.Header[key] = values
+		}
+		stdout := captureStdout(t, func() {
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		})
+		server.Close()
+		file.Close()
+
+		content, _ := os.ReadFile(file.Name())
+		if hasNote := strings.Contains(stdout, "not recorded: contains"); hasNote != tt.skipped {
+			t.Errorf("%s: output %q", tt.name, stdout)
+		}
+		if tt.skipped {
+			// Replaying it would send the interpolated text instead.
+			if len(content) > 0 {
+				t.Errorf("%s: recorded %q", tt.name, content)
+			}
+			continue
+		}
+		text := strings.TrimSuffix(string(content), "\n")
+		if strings.Contains(text, "\n") {
+			t.Errorf("%s: recorded over several lines: %q", tt.name, content)
+			continue
+		}
+		got, err := parseLine(text)
+		if err != nil {
+			t.Errorf("%s: %s: %v", tt.name, text, err)
+			continue
+		}
+		var headers []string
+		for _, header := range got.Headers {
+			if !strings.HasPrefix(header, "User-Agent:") {
+				headers = append(headers, header)
+			}
+		}
+		if got.URL != target.URL+"/x?q=1" || got.Method != "POST" || got.Body != tt.want.Body ||
+			strings.Join(headers, "|") != strings.Join(tt.want.Headers, "|") {
+			t.Errorf("%s: recorded %s", tt.name, text)
+		}
+	}
+}
+
+// captureStdout returns what f prints.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,181 @@
+// cmd/redirect.go
+//
+// Redirects are followed here rather than by http.Client, so every
+// command (including the RoundTrip based stress commands) follows them
+// the same way and each hop can be reported with its own timing.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type redirectOptions struct {
+	Follow       bool
+	NoFollow     bool
+	MaxRedirects int
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.Redirects.Follow, "follow", true, "Follow redirects")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.Redirects.NoFollow, "no-follow", false, "Don't follow redirects")
+	rootCmd.PersistentFlags().IntVar(&transportFlags.Redirects.MaxRedirects, "max-redirects", 10, "Maximum number of redirects to follow")
+}
+
+type redirectHop struct {
+	Status   string
+	URL      string
+	Location string
+	Duration time.Duration
+}
+
+func (opts redirectOptions) wrap(rt http.RoundTripper) http.RoundTripper {
+	if !opts.Follow || opts.NoFollow {
+		return rt
+	}
+	return &redirectTransport{rt: rt, max: opts.MaxRedirects}
+}
+
+type redirectTransport struct {
+	rt  http.RoundTripper
+	max int
+}
+
+type originKey struct{}
+
+// sameOrigin reports whether req still goes to the host its redirect
+// chain started at. Credentials are only added when it does, the layers
+// adding them sit inside the redirect transport and would otherwise
+// hand them to whatever host a redirect points at.
+func sameOrigin(req *http.Request) bool {
+	origin, ok := req.Context().Value(originKey{}).(string)
+	return !ok || strings.EqualFold(origin, req.URL.Host)
+}
+
+// originOnly sends requests through creds while sameOrigin holds and
+// straight to rt once a redirect leaves the origin.
+type originOnly struct {
+	creds, rt http.RoundTripper
+}
+
+func (t *originOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if sameOrigin(req) {
+		return t.creds.RoundTrip(req)
+	}
+	return t.rt.RoundTrip(req)
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// nextRequest builds the request for the next hop the way browsers and
+// http.Client do: 301/302/303 turn into a bodiless GET, 307/308 repeat
+// the method and body. It reports false if the body can't be replayed.
+func nextRequest(req *http.Request, status int, next *url.URL) (*http.Request, bool) {
+	var hop *http.Request
+	if status == http.StatusTemporaryRedirect || status == http.StatusPermanentRedirect {
+		var ok bool
+		if hop, ok = rewind(req); !ok {
+			return nil, false
+		}
+	} else {
+		hop = req.Clone(req.Context())
+		if req.Method != "GET" && req.Method != "HEAD" {
+			hop.Method = "GET"
+			hop.Body = nil
+			hop.GetBody = nil
+			hop.ContentLength = 0
+			hop.Header.Del("Content-Type")
+			hop.Header.Del("Content-Length")
+		}
+	}
+
+	if !strings.EqualFold(next.Host, req.URL.Host) {
+		hop.Header.Del("Authorization")
+		hop.Header.Del("Cookie")
+	}
+	hop.URL = next
+	hop.Host = ""
+	return hop, true
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	measured := measuredFromContext(req.Context())
+	chain := []string{req.URL.String()}
+	hopReq := req.WithContext(context.WithValue(req.Context(), originKey{}, req.URL.Host))
+
+	for {
+		start := time.Now()
+		resp, err := t.rt.RoundTrip(hopReq)
+		if err != nil {
+			return nil, err
+		}
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			return resp, nil
+		}
+		next, err := hopReq.URL.Parse(location)
+		if err != nil {
+			return resp, nil
+		}
+
+		if measured != nil {
+			measured.Redirects = append(measured.Redirects, redirectHop{
+				Status:   resp.Status,
+				URL:      hopReq.URL.String(),
+				Location: next.String(),
+				Duration: time.Since(start),
+			})
+		}
+		chain = append(chain, next.String())
+
+		if len(chain)-1 > t.max {
+			drainAndClose(resp.Body)
+			return nil, redirectLimitError(chain, t.max)
+		}
+		nextReq, ok := nextRequest(hopReq, resp.StatusCode, next)
+		if !ok {
+			return resp, nil
+		}
+		drainAndClose(resp.Body)
+		hopReq = nextReq
+	}
+}
+
+// redirectLimitError explains why following stopped, calling out loops
+// since those are the usual cause.
+func redirectLimitError(chain []string, max int) error {
+	seen := make(map[string]bool)
+	for _, hop := range chain {
+		if seen[hop] {
+			return fmt.Errorf("redirect loop after %d redirects: %s", max, strings.Join(chain, " -> "))
+		}
+		seen[hop] = true
+	}
+	return fmt.Errorf("stopped after %d redirects: %s", max, strings.Join(chain, " -> "))
+}
+
+// formatRedirects lists the hops taken before the final response, one
+// per line.
+func formatRedirects(measured measuredResponse) string {
+	var b strings.Builder
+	for i, hop := range measured.Redirects {
+		fmt.Fprintf(&b, "Redirect %d: %s %s -> %s (%v)\n", i+1, hop.Status, hop.URL, hop.Location, hop.Duration)
+	}
+	return b.String()
+}
+
+func printRedirects(measured measuredResponse) {
+	fmt.Print(formatRedirects(measured))
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRedirectDropsCredentials follows a redirect to a second server and
+// checks that none of the credential layers add their headers there.
+func TestRedirectDropsCredentials(t *testing.T) {
+	var seen []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization")+"|"+r.Header.Get("Cookie")+"|"+r.Header.Get("X-Amz-Date"))
+	}))
+	defer other.Close()
+
+	var originAuth string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "oauth-token", "expires_in": 3600})
+		case "/local":
+			originAuth = r.Header.Get("Authorization")
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		case "/elsewhere":
+			http.Redirect(w, r, other.URL+"/landing", http.StatusFound)
+		default:
+			http.Redirect(w, r, other.URL+"/landing", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name string
+		cfg  transportConfig
+	}{
+		{"basic", transportConfig{Auth: authOptions{Basic: "user:secret"}}},
+		{"bearer", transportConfig{Auth: authOptions{Bearer: "secret"}}},
+		{"digest", transportConfig{Auth: authOptions{Digest: "user:secret"}}},
+		{"oauth2", transportConfig{Auth: authOptions{OAuth2TokenURL: origin.URL + "/token", OAuth2ClientID: "id", OAuth2ClientSecret: "secret"}}},
+		{"sigv4", transportConfig{Signing: signingOptions{AWSSigV4: "us-east-1:service", AWSAccessKey: "AKID", AWSSecretKey: "secret"}}},
+		{"hmac", transportConfig{Signing: signingOptions{HMACKey: "secret", HMACHeader: "Authorization", HMACTimestampHeader: "X-Amz-Date", HMACAlgorithm: "sha256"}}},
+		{"cookie", transportConfig{Cookies: cookieOptions{Values: []string{"session=secret"}}}},
+	}
+	for _, tt := range tests {
+		tt.cfg.KeepAlive = true
+		tt.cfg.Redirects = redirectOptions{Follow: true, MaxRedirects: 10}
+		rt, err := newTransport(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, path := range []string{"/", "/local"} {
+			seen, originAuth = nil, ""
+			req, _ := http.NewRequest("GET", origin.URL+path, nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.name, path, err)
+			}
+			resp.Body.Close()
+			if len(seen) != 1 || seen[0] != "||" {
+				t.Errorf("%s %s: other host received %q", tt.name, path, seen)
+			}
+			if path == "/local" && originAuth == "" && tt.name != "digest" && tt.name != "cookie" {
+				t.Errorf("%s: origin received no Authorization", tt.name)
+			}
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"github.com/spf13/cobra"
 	"strings"
-	"path/filepath"
 )
 
 var removeCmd = &cobra.Command{
@@ -20,7 +19,11 @@ var removeCmd = &cobra.Command{
 			fileName = fileName + ".txt"
 		}
 
-		filePath := filepath.Join("executable", fileName)
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
 		if _, err := os.Stat(filePath); err == nil {
 			err := os.Remove(filePath)
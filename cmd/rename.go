@@ -0,0 +1,59 @@
+// cmd/rename.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename [fileName] [newFileName]",
+	Short: "Renames a txt file in the workspace",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName, newFileName := args[0], args[1]
+		if !strings.HasSuffix(fileName, ".txt") {
+			fileName = fileName + ".txt"
+		}
+		if !strings.HasSuffix(newFileName, ".txt") {
+			newFileName = newFileName + ".txt"
+		}
+
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		newFilePath, err := suitePath(newFileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(newFilePath), os.ModePerm); err != nil {
+			fmt.Println("Error creating directory:", err)
+			return
+		}
+
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			fmt.Println("File does not exist:", fileName)
+			return
+		}
+		if _, err := os.Stat(newFilePath); err == nil {
+			fmt.Println("File already exists:", newFileName)
+			return
+		}
+		if err := os.Rename(filePath, newFilePath); err != nil {
+			fmt.Println("Error renaming file:", err)
+			return
+		}
+		fmt.Printf("Renamed %s to %s\n", fileName, newFileName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
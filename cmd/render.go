@@ -0,0 +1,461 @@
+// cmd/render.go
+//
+// Response rendering for the interactive commands (get, getc, post,
+// postc). Headers are printed one per line in sorted order and bodies are
+// pretty-printed by Content-Type. Each response is rendered into a buffer
+// and written in one go, so concurrent requests don't interleave.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/html"
+)
+
+var renderFlags struct {
+	Include     bool
+	BodyOnly    bool
+	HeadersOnly bool
+	NoColor     bool
+}
+
+// addRenderFlags registers the output flags on a command that prints
+// responses. The flags are shared, only one command runs at a time.
+func addRenderFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&renderFlags.Include, "include", false, "Print status, headers and body (default)")
+	cmd.Flags().BoolVar(&renderFlags.BodyOnly, "body-only", false, "Print only the response body")
+	cmd.Flags().BoolVar(&renderFlags.HeadersOnly, "headers-only", false, "Print only the status line and headers")
+	cmd.Flags().BoolVar(&renderFlags.NoColor, "no-color", false, "Disable colored output")
+}
+
+func validateRenderFlags() error {
+	modes := 0
+	for _, set := range []bool{renderFlags.Include, renderFlags.BodyOnly, renderFlags.HeadersOnly} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("--include, --body-only and --headers-only are mutually exclusive")
+	}
+	return nil
+}
+
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+// useColor reports whether output should be colored: not disabled by
+// --no-color or $NO_COLOR, and stdout is a terminal.
+func useColor() bool {
+	if renderFlags.NoColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(realStdout())
+}
+
+type painter bool
+
+func (p painter) paint(color, s string) string {
+	if !p || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+var outputMu sync.Mutex
+
+// printResponse renders measured and its already read body to stdout.
+func printResponse(measured measuredResponse, body []byte) {
+	var b bytes.Buffer
+	renderResponse(&b, measured, body, painter(useColor()))
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	os.Stdout.Write(b.Bytes())
+}
+
+func renderResponse(w io.Writer, measured measuredResponse, body []byte, p painter) {
+	resp := measured.Res
+	if !renderFlags.BodyOnly {
+		fmt.Fprint(w, formatRedirects(measured))
+		if summary := tlsSummary(measured); summary != "" {
+			fmt.Fprintln(w, p.paint(colorGray, "TLS: "+summary))
+		}
+		if measured.Body != nil && measured.Body.Encoding != "identity" {
+			fmt.Fprintln(w, p.paint(colorGray, "Body Size: "+bodySummary(measured)))
+		}
+		fmt.Fprintln(w, p.paint(statusColor(resp.StatusCode), resp.Proto+" "+resp.Status))
+		renderHeaders(w, resp.Header, p)
+	}
+	if renderFlags.HeadersOnly {
+		return
+	}
+	if !renderFlags.BodyOnly {
+		fmt.Fprintln(w)
+	}
+	if rendered := renderBody(resp.Header.Get("Content-Type"), body, p); rendered != "" {
+		fmt.Fprint(w, rendered)
+		if !strings.HasSuffix(rendered, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+func statusColor(code int) string {
+	switch {
+	case code >= 400:
+		return colorRed
+	case code >= 300:
+		return colorYellow
+	default:
+		return colorGreen
+	}
+}
+
+// renderHeaders prints one "Name: value" line per value, names sorted.
+func renderHeaders(w io.Writer, header http.Header, p painter) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(w, "%s: %s\n", p.paint(colorCyan, name), value)
+		}
+	}
+}
+
+func isJSONType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXMLType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func isBinary(mediaType string, body []byte) bool {
+	if strings.HasPrefix(mediaType, "text/") || isJSONType(mediaType) || isXMLType(mediaType) {
+		return bytes.IndexByte(body, 0) >= 0
+	}
+	for _, family := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mediaType, family) {
+			return true
+		}
+	}
+	return !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0
+}
+
+// renderBody pretty-prints body according to contentType, falling back
+// to the raw text when it doesn't parse. Binary bodies are summarized.
+func renderBody(contentType string, body []byte, p painter) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch {
+	case isBinary(mediaType, body):
+		return p.paint(colorGray, fmt.Sprintf("[binary body: %d bytes, %s]", len(body), mediaType))
+	case isJSONType(mediaType) || (mediaType == "text/plain" && json.Valid(body)):
+		var out bytes.Buffer
+		if err := json.Indent(&out, body, "", "  "); err == nil {
+			return colorizeJSON(out.String(), p)
+		}
+	case isXMLType(mediaType):
+		if pretty, err := prettyXML(body); err == nil {
+			return colorizeMarkup(pretty, p)
+		}
+	case mediaType == "text/html":
+		if pretty, err := prettyHTML(body); err == nil {
+			return colorizeMarkup(pretty, p)
+		}
+	}
+	return string(body)
+}
+
+// colorizeJSON colors already indented JSON: keys, strings, numbers and
+// literals each get their own color.
+func colorizeJSON(s string, p painter) string {
+	if !p {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			color := colorGreen
+			if rest := strings.TrimLeft(s[end:], " "); strings.HasPrefix(rest, ":") {
+				color = colorBlue
+			}
+			b.WriteString(p.paint(color, s[i:end]))
+			i = end
+		case c == '-' || ('0' <= c && c <= '9'):
+			end := i
+			for end < len(s) && strings.IndexByte("+-.eE0123456789", s[end]) >= 0 {
+				end++
+			}
+			b.WriteString(p.paint(colorCyan, s[i:end]))
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(s) && 'a' <= s[end] && s[end] <= 'z' {
+				end++
+			}
+			b.WriteString(p.paint(colorMagenta, s[i:end]))
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func xmlStartTag(start xml.StartElement) string {
+	var b strings.Builder
+	b.WriteString("<" + xmlName(start.Name))
+	for _, attr := range start.Attr {
+		fmt.Fprintf(&b, ` %s="%s"`, xmlName(attr.Name), xmlEscape(attr.Value))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// prettyXML re-indents an XML document, one element per line. Elements
+// holding only text stay on a single line and whitespace-only text is
+// dropped. Namespace prefixes are kept as written.
+func prettyXML(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	var tokens []xml.Token
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if text, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		if end, ok := tokens[i].(xml.EndElement); ok {
+			if depth > 0 {
+				depth--
+			}
+			fmt.Fprintf(&b, "%s</%s>\n", strings.Repeat("  ", depth), xmlName(end.Name))
+			continue
+		}
+		indent := strings.Repeat("  ", depth)
+		switch t := tokens[i].(type) {
+		case xml.StartElement:
+			open := xmlStartTag(t)
+			if i+1 < len(tokens) {
+				if _, ok := tokens[i+1].(xml.EndElement); ok {
+					fmt.Fprintf(&b, "%s%s/>\n", indent, strings.TrimSuffix(open, ">"))
+					i++
+					continue
+				}
+			}
+			if i+2 < len(tokens) {
+				text, isText := tokens[i+1].(xml.CharData)
+				end, isEnd := tokens[i+2].(xml.EndElement)
+				if isText && isEnd {
+					fmt.Fprintf(&b, "%s%s%s</%s>\n", indent, open, xmlEscape(strings.TrimSpace(string(text))), xmlName(end.Name))
+					i += 2
+					continue
+				}
+			}
+			fmt.Fprintf(&b, "%s%s\n", indent, open)
+			depth++
+		case xml.CharData:
+			fmt.Fprintf(&b, "%s%s\n", indent, xmlEscape(strings.TrimSpace(string(t))))
+		case xml.Comment:
+			fmt.Fprintf(&b, "%s<!--%s-->\n", indent, t)
+		case xml.ProcInst:
+			fmt.Fprintf(&b, "%s<?%s %s?>\n", indent, t.Target, t.Inst)
+		case xml.Directive:
+			fmt.Fprintf(&b, "%s<!%s>\n", indent, t)
+		}
+	}
+	return b.String(), nil
+}
+
+// Elements whose content is kept as is rather than re-indented.
+var htmlVerbatim = map[string]bool{"pre": true, "textarea": true, "script": true, "style": true}
+
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+func prettyHTML(body []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		writeHTMLNode(&b, n, 0)
+	}
+	return b.String(), nil
+}
+
+func htmlStartTag(n *html.Node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.Data)
+	for _, attr := range n.Attr {
+		fmt.Fprintf(&b, ` %s="%s"`, attr.Key, html.EscapeString(attr.Val))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func writeHTMLNode(b *strings.Builder, n *html.Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch n.Type {
+	case html.DoctypeNode:
+		fmt.Fprintf(b, "%s<!DOCTYPE %s>\n", indent, n.Data)
+	case html.CommentNode:
+		fmt.Fprintf(b, "%s<!--%s-->\n", indent, n.Data)
+	case html.TextNode:
+		if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+			fmt.Fprintf(b, "%s%s\n", indent, html.EscapeString(text))
+		}
+	case html.ElementNode:
+		switch {
+		case htmlVoid[n.Data]:
+			fmt.Fprintf(b, "%s%s\n", indent, htmlStartTag(n))
+		case htmlVerbatim[n.Data]:
+			b.WriteString(indent)
+			html.Render(b, n)
+			b.WriteString("\n")
+		case n.FirstChild == nil:
+			fmt.Fprintf(b, "%s%s</%s>\n", indent, htmlStartTag(n), n.Data)
+		case n.FirstChild == n.LastChild && n.FirstChild.Type == html.TextNode:
+			text := strings.Join(strings.Fields(n.FirstChild.Data), " ")
+			fmt.Fprintf(b, "%s%s%s</%s>\n", indent, htmlStartTag(n), html.EscapeString(text), n.Data)
+		default:
+			fmt.Fprintf(b, "%s%s\n", indent, htmlStartTag(n))
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				writeHTMLNode(b, child, depth+1)
+			}
+			fmt.Fprintf(b, "%s</%s>\n", indent, n.Data)
+		}
+	}
+}
+
+var markupAttr = regexp.MustCompile(`([^\s="'<>/]+)(\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+
+// colorizeMarkup colors tags, attributes and comments in XML or HTML.
+func colorizeMarkup(s string, p painter) string {
+	if !p {
+		return s
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:start])
+		s = s[start:]
+
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				end = len(s)
+			} else {
+				end += len("-->")
+			}
+			b.WriteString(p.paint(colorGray, s[:end]))
+			s = s[end:]
+			continue
+		}
+
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			b.WriteString(s)
+			break
+		}
+		tag := s[:end+1]
+		s = s[end+1:]
+
+		nameEnd := strings.IndexAny(tag, " \t\n>")
+		closing := ">"
+		if strings.HasSuffix(tag, "/>") || strings.HasSuffix(tag, "?>") {
+			closing = tag[len(tag)-2:]
+		}
+		// In "<b/>" the name ends where the closing starts.
+		attrsEnd := len(tag) - len(closing)
+		if nameEnd > attrsEnd {
+			nameEnd = attrsEnd
+		}
+		attrs := tag[nameEnd:attrsEnd]
+		b.WriteString(p.paint(colorBlue, tag[:nameEnd]))
+		b.WriteString(markupAttr.ReplaceAllStringFunc(attrs, func(attr string) string {
+			parts := markupAttr.FindStringSubmatch(attr)
+			return p.paint(colorYellow, parts[1]) + parts[2] + p.paint(colorGreen, parts[3])
+		}))
+		b.WriteString(p.paint(colorBlue, closing))
+	}
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColorizeMarkup(t *testing.T) {
+	// Colors spelled out so the expectations stay readable.
+	plain := strings.NewReplacer(colorBlue, "{b}", colorYellow, "{y}", colorGreen, "{g}", colorGray, "{c}", colorReset, "{/}")
+	tests := []struct{ in, want string }{
+		{`<b/>`, `{b}<b{/}{b}/>{/}`},
+		{`<a x="1"/>`, `{b}<a{/} {y}x{/}={g}"1"{/}{b}/>{/}`},
+		{`<?xml version="1.0" encoding="UTF-8"?>`, `{b}<?xml{/} {y}version{/}={g}"1.0"{/} {y}encoding{/}={g}"UTF-8"{/}{b}?>{/}`},
+		{`<a>text</a>`, `{b}<a{/}{b}>{/}text{b}</a{/}{b}>{/}`},
+		{`<>`, `{b}<{/}{b}>{/}`},
+		{`<!-- note --><br>`, `{c}<!-- note -->{/}{b}<br{/}{b}>{/}`},
+		{`1 < 2`, `1 < 2`},
+	}
+	for _, tt := range tests {
+		if got := plain.Replace(colorizeMarkup(tt.in, true)); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.in, got, tt.want)
+		}
+		if got := colorizeMarkup(tt.in, false); got != tt.in {
+			t.Errorf("%s without color: got %s", tt.in, got)
+		}
+	}
+}
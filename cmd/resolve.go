@@ -0,0 +1,142 @@
+// cmd/resolve.go
+//
+// Name resolution for every transport. --resolve pins a host to an IP
+// (no lookup at all), --dns-server sends lookups to a specific server
+// and --dns-cache reuses the first answer for the rest of the run, so
+// DNS cost can be isolated or taken out of the measurements entirely.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&transportFlags.Resolve, "resolve", nil, "HOST:IP, skip DNS and use IP for HOST (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.DNSServer, "dns-server", "", "DNS server to query instead of the system resolver, e.g. 1.1.1.1:53")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.DNSCache, "dns-cache", false, "Resolve each host once and reuse the answer for the whole run")
+}
+
+// dnsCache is shared by every transport in the process, keyed by DNS
+// server and lowercased host.
+var dnsCache struct {
+	sync.Mutex
+	entries map[string][]net.IP
+}
+
+type hostResolver struct {
+	overrides map[string]net.IP
+	resolver  *net.Resolver
+	server    string
+	cache     bool
+}
+
+// resolvesItself reports whether any resolution flag is set, so dials
+// have to go through a hostResolver.
+func (cfg transportConfig) resolvesItself() bool {
+	return len(cfg.Resolve) > 0 || cfg.DNSServer != "" || cfg.DNSCache
+}
+
+func (cfg transportConfig) hostResolver() (*hostResolver, error) {
+	hr := &hostResolver{
+		overrides: make(map[string]net.IP),
+		resolver:  net.DefaultResolver,
+		cache:     cfg.DNSCache,
+	}
+	for _, raw := range cfg.Resolve {
+		host, ip, ok := strings.Cut(raw, ":")
+		parsed := net.ParseIP(strings.Trim(ip, "[]"))
+		if !ok || host == "" || parsed == nil {
+			return nil, fmt.Errorf("invalid --resolve %q, expected HOST:IP", raw)
+		}
+		hr.overrides[strings.ToLower(host)] = parsed
+	}
+
+	if cfg.DNSServer != "" {
+		server := cfg.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		hr.server = server
+		dialer := newDialer()
+		hr.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return hr, nil
+}
+
+// lookup returns the addresses to try for host. Lookups run with the
+// request's context so the httptrace DNS hooks fire; overrides and cache
+// hits don't, and show up as zero DNS time.
+func (hr *hostResolver) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if ip, ok := hr.overrides[strings.ToLower(host)]; ok {
+		return []net.IP{ip}, nil
+	}
+
+	key := hr.server + " " + strings.ToLower(host)
+	if hr.cache {
+		dnsCache.Lock()
+		ips, ok := dnsCache.entries[key]
+		dnsCache.Unlock()
+		if ok {
+			return ips, nil
+		}
+	}
+
+	addrs, err := hr.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+
+	if hr.cache {
+		dnsCache.Lock()
+		if dnsCache.entries == nil {
+			dnsCache.entries = make(map[string][]net.IP)
+		}
+		dnsCache.entries[key] = ips
+		dnsCache.Unlock()
+	}
+	return ips, nil
+}
+
+// dial resolves addr's host and tries each address in turn.
+func (hr *hostResolver) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := hr.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, firstErr
+}
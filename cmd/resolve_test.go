@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestHostResolverLookup(t *testing.T) {
+	hr, err := transportConfig{Resolve: []string{"Pinned.test:10.0.0.1", "v6.test:[::1]"}, DNSCache: true}.hostResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnsCache.Lock()
+	dnsCache.entries = map[string][]net.IP{
+		" cached.test":            {net.ParseIP("10.0.0.2")},
+		"10.9.9.9:53 cached.test": {net.ParseIP("10.0.0.3")},
+	}
+	dnsCache.Unlock()
+	defer func() {
+		dnsCache.Lock()
+		dnsCache.entries = nil
+		dnsCache.Unlock()
+	}()
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"pinned.test", "10.0.0.1"},
+		{"PINNED.test", "10.0.0.1"},
+		{"v6.test", "::1"},
+		{"192.0.2.7", "192.0.2.7"},
+		{"cached.test", "10.0.0.2"},
+		{"Cached.Test", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		ips, err := hr.lookup(context.Background(), tt.host)
+		if err != nil {
+			t.Errorf("%s: %v", tt.host, err)
+			continue
+		}
+		if len(ips) != 1 || ips[0].String() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.host, ips, tt.want)
+		}
+	}
+
+	// Each --dns-server has its own cache entries.
+	other, err := transportConfig{DNSServer: "10.9.9.9", DNSCache: true}.hostResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := other.lookup(context.Background(), "cached.test")
+	if err != nil || len(ips) != 1 || ips[0].String() != "10.0.0.3" {
+		t.Errorf("cached.test via 10.9.9.9: got %v, %v", ips, err)
+	}
+}
+
+func TestInvalidResolve(t *testing.T) {
+	for _, raw := range []string{"host", ":10.0.0.1", "host:not-an-ip"} {
+		if _, err := (transportConfig{Resolve: []string{raw}}).hostResolver(); err == nil {
+			t.Errorf("--resolve %q: expected an error", raw)
+		}
+	}
+}
@@ -5,7 +5,10 @@ Copyright © 2024 NAME HERE chenleejonathan@gmail.com
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"runtime/debug"
+
 	"github.com/spf13/cobra"
 )
 
@@ -13,12 +16,32 @@ var rootCmd = &cobra.Command{
 	Use:   "hpgo",
 	Short: "A http cli tool",
 	Long: "HTTP CLIgo - a simple http cli tool in Go for basic/custom requests, api testing, debugging, etc.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyProfile(cmd); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
 }
 
 func Execute() {
+	// Deferred so a command that panics still saves its cookie jars and
+	// exports its spans.
+	defer func() {
+		if r := recover(); r != nil {
+			// The runtime would print straight to the real stderr, past
+			// the secret masking.
+			fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, debug.Stack())
+			exit(2)
+		}
+		saveCookieJars()
+		flushSpans()
+		stopMasking()
+	}()
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		exit(1)
 	}
 }
 
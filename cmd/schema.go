@@ -0,0 +1,570 @@
+// cmd/schema.go
+//
+// A JSON Schema validator for response bodies, covering the keywords
+// OpenAPI 3 schema objects and plain JSON Schema documents use in
+// practice. OpenAPI 3.0 quirks (nullable, boolean exclusiveMinimum) are
+// accepted next to their JSON Schema forms. Each violation carries the
+// JSON pointer of the offending value.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaDoc is a loaded JSON or YAML document refs resolve against.
+type schemaDoc struct {
+	root any
+	path string
+	// refOnly is set when $ref replaces the keywords next to it, as in
+	// OpenAPI 3.0 and JSON Schema drafts before 2019-09.
+	refOnly bool
+}
+
+type schemaViolation struct {
+	Pointer string
+	Message string
+}
+
+func (v schemaViolation) String() string {
+	return "#" + v.Pointer + ": " + v.Message
+}
+
+// schemaValidator validates decoded JSON against schemas. It is safe for
+// concurrent use; external documents and patterns are cached.
+type schemaValidator struct {
+	mu       sync.Mutex
+	docs     map[string]*schemaDoc
+	patterns sync.Map
+}
+
+func newSchemaValidator() *schemaValidator {
+	return &schemaValidator{docs: make(map[string]*schemaDoc)}
+}
+
+// loadDocument reads a JSON or YAML file, with maps keyed by string.
+func (v *schemaValidator) loadDocument(path string) (*schemaDoc, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if doc, ok := v.docs[path]; ok {
+		return doc, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	root = normalizeYAML(root)
+	doc := &schemaDoc{root: root, path: path, refOnly: refReplacesSiblings(root)}
+	v.docs[path] = doc
+	return doc, nil
+}
+
+// refReplacesSiblings reports whether the document is OpenAPI 3.0,
+// Swagger 2 or declares a JSON Schema draft older than 2019-09. Plain
+// schemas without $schema get the current behaviour.
+func refReplacesSiblings(root any) bool {
+	fields, _ := root.(map[string]any)
+	if version, ok := fields["openapi"].(string); ok {
+		return strings.HasPrefix(version, "3.0")
+	}
+	if _, ok := fields["swagger"]; ok {
+		return true
+	}
+	dialect, _ := fields["$schema"].(string)
+	return strings.Contains(dialect, "/draft-0")
+}
+
+// normalizeYAML turns the map[any]any yaml.v3 produces for non-string
+// keys (like response codes) into map[string]any, and ints into float64
+// so values compare like decoded JSON.
+func normalizeYAML(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeYAML(item)
+		}
+		return value
+	case map[any]any:
+		converted := make(map[string]any, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return converted
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeYAML(item)
+		}
+		return value
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	}
+	return value
+}
+
+// resolvePointer follows a JSON pointer ("/components/schemas/Pet").
+func resolvePointer(root any, pointer string) (any, bool) {
+	if pointer == "" {
+		return root, true
+	}
+	node := root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token, _ = url.PathUnescape(token)
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch current := node.(type) {
+		case map[string]any:
+			next, ok := current[token]
+			if !ok {
+				return nil, false
+			}
+			node = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(current) {
+				return nil, false
+			}
+			node = current[i]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// resolveRef returns the schema a $ref points to and the document it
+// lives in. Refs are "#/pointer", "file.yaml" or "file.yaml#/pointer",
+// files relative to the referring document.
+func (v *schemaValidator) resolveRef(doc *schemaDoc, ref string) (any, *schemaDoc, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	target := doc
+	if file != "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(doc.path), file)
+		}
+		var err error
+		if target, err = v.loadDocument(file); err != nil {
+			return nil, nil, fmt.Errorf("$ref %s: %w", ref, err)
+		}
+	}
+	node, ok := resolvePointer(target.root, pointer)
+	if !ok {
+		return nil, nil, fmt.Errorf("$ref %s not found", ref)
+	}
+	return node, target, nil
+}
+
+// validate checks value against schema, a node of doc.
+func (v *schemaValidator) validate(doc *schemaDoc, schema any, value any) []schemaViolation {
+	var violations []schemaViolation
+	v.check(doc, schema, value, "", &violations, 0)
+	return violations
+}
+
+// matches reports whether value satisfies schema, for anyOf/oneOf/not.
+func (v *schemaValidator) matches(doc *schemaDoc, schema any, value any, pointer string, depth int) bool {
+	var violations []schemaViolation
+	v.check(doc, schema, value, pointer, &violations, depth)
+	return len(violations) == 0
+}
+
+const maxSchemaDepth = 200
+
+func (v *schemaValidator) check(doc *schemaDoc, schema any, value any, pointer string, out *[]schemaViolation, depth int) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, schemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+	if depth > maxSchemaDepth {
+		fail("schema nests too deeply (circular $ref?)")
+		return
+	}
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			fail("no value is allowed here")
+		}
+		return
+	}
+	s, ok := schema.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, targetDoc, err := v.resolveRef(doc, ref)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		v.check(targetDoc, target, value, pointer, out, depth+1)
+		if doc.refOnly {
+			return
+		}
+	}
+
+	for _, sub := range schemaList(s["allOf"]) {
+		v.check(doc, sub, value, pointer, out, depth+1)
+	}
+	if branches := schemaList(s["anyOf"]); len(branches) > 0 {
+		matched := false
+		for _, sub := range branches {
+			if v.matches(doc, sub, value, pointer, depth+1) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("matches none of the anyOf schemas")
+		}
+	}
+	if branches := schemaList(s["oneOf"]); len(branches) > 0 {
+		matched := 0
+		for _, sub := range branches {
+			if v.matches(doc, sub, value, pointer, depth+1) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("matches %d of the oneOf schemas, expected exactly 1", matched)
+		}
+	}
+	if not, ok := s["not"]; ok && v.matches(doc, not, value, pointer, depth+1) {
+		fail("matches the schema in not")
+	}
+
+	if value == nil && s["nullable"] == true {
+		return
+	}
+	if types := schemaTypes(s["type"]); len(types) > 0 {
+		actual := jsonType(value)
+		allowed := false
+		for _, t := range types {
+			if t == actual || t == "number" && actual == "integer" {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			fail("expected %s, got %s", strings.Join(types, " or "), actual)
+			return
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, option := range enum {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("%s is not one of the allowed values", compactJSON(value))
+		}
+	}
+	if constant, ok := s["const"]; ok && !jsonEqual(constant, value) {
+		fail("expected %s, got %s", compactJSON(constant), compactJSON(value))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.checkObject(doc, s, value, pointer, out, depth, fail)
+	case []any:
+		v.checkArray(doc, s, value, pointer, out, depth, fail)
+	case string:
+		v.checkString(s, value, fail)
+	case float64:
+		checkNumber(s, value, fail)
+	}
+}
+
+func (v *schemaValidator) checkObject(doc *schemaDoc, s map[string]any, value map[string]any, pointer string, out *[]schemaViolation, depth int, fail func(string, ...any)) {
+	if required, ok := s["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := value[name]; !present {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+	}
+	if n, ok := toFloat(s["minProperties"]); ok && float64(len(value)) < n {
+		fail("has %d properties, fewer than %v", len(value), n)
+	}
+	if n, ok := toFloat(s["maxProperties"]); ok && float64(len(value)) > n {
+		fail("has %d properties, more than %v", len(value), n)
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	patterns, _ := s["patternProperties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := pointer + "/" + escapePointer(name)
+		known := false
+		if sub, ok := properties[name]; ok {
+			known = true
+			v.check(doc, sub, value[name], child, out, depth+1)
+		}
+		for pattern, sub := range patterns {
+			if re := v.pattern(pattern); re != nil && re.MatchString(name) {
+				known = true
+				v.check(doc, sub, value[name], child, out, depth+1)
+			}
+		}
+		if known {
+			continue
+		}
+		switch additional := s["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*out = append(*out, schemaViolation{Pointer: child, Message: "property is not allowed"})
+			}
+		case map[string]any:
+			v.check(doc, additional, value[name], child, out, depth+1)
+		}
+	}
+}
+
+func (v *schemaValidator) checkArray(doc *schemaDoc, s map[string]any, value []any, pointer string, out *[]schemaViolation, depth int, fail func(string, ...any)) {
+	if n, ok := toFloat(s["minItems"]); ok && float64(len(value)) < n {
+		fail("has %d items, fewer than %v", len(value), n)
+	}
+	if n, ok := toFloat(s["maxItems"]); ok && float64(len(value)) > n {
+		fail("has %d items, more than %v", len(value), n)
+	}
+	if s["uniqueItems"] == true {
+	unique:
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if jsonEqual(value[i], value[j]) {
+					fail("items %d and %d are equal, items must be unique", i, j)
+					break unique
+				}
+			}
+		}
+	}
+
+	// prefixItems (2020-12) or an items array (older drafts) fix the
+	// leading items, items or additionalItems covers the rest.
+	prefix := schemaList(s["prefixItems"])
+	rest := s["items"]
+	if tuple := schemaList(s["items"]); tuple != nil {
+		prefix, rest = tuple, s["additionalItems"]
+	}
+	for i, item := range value {
+		child := pointer + "/" + strconv.Itoa(i)
+		switch {
+		case i < len(prefix):
+			v.check(doc, prefix[i], item, child, out, depth+1)
+		case rest != nil:
+			v.check(doc, rest, item, child, out, depth+1)
+		}
+	}
+	if contains, ok := s["contains"]; ok {
+		found := false
+		for i, item := range value {
+			if v.matches(doc, contains, item, pointer+"/"+strconv.Itoa(i), depth+1) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("no item matches contains")
+		}
+	}
+}
+
+func (v *schemaValidator) checkString(s map[string]any, value string, fail func(string, ...any)) {
+	length := float64(utf8.RuneCountInString(value))
+	if n, ok := toFloat(s["minLength"]); ok && length < n {
+		fail("length %v is shorter than %v", length, n)
+	}
+	if n, ok := toFloat(s["maxLength"]); ok && length > n {
+		fail("length %v is longer than %v", length, n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re := v.pattern(pattern); re != nil && !re.MatchString(value) {
+			fail("%q does not match pattern %s", value, pattern)
+		}
+	}
+	if format, ok := s["format"].(string); ok && !validFormat(format, value) {
+		fail("%q is not a valid %s", value, format)
+	}
+}
+
+func checkNumber(s map[string]any, value float64, fail func(string, ...any)) {
+	if n, ok := toFloat(s["minimum"]); ok {
+		if s["exclusiveMinimum"] == true && value <= n {
+			fail("%v is not greater than %v", value, n)
+		} else if value < n {
+			fail("%v is less than the minimum %v", value, n)
+		}
+	}
+	if n, ok := toFloat(s["maximum"]); ok {
+		if s["exclusiveMaximum"] == true && value >= n {
+			fail("%v is not less than %v", value, n)
+		} else if value > n {
+			fail("%v is greater than the maximum %v", value, n)
+		}
+	}
+	if n, ok := toFloat(s["exclusiveMinimum"]); ok && value <= n {
+		fail("%v is not greater than %v", value, n)
+	}
+	if n, ok := toFloat(s["exclusiveMaximum"]); ok && value >= n {
+		fail("%v is not less than %v", value, n)
+	}
+	if n, ok := toFloat(s["multipleOf"]); ok && n > 0 {
+		if q := value / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("%v is not a multiple of %v", value, n)
+		}
+	}
+}
+
+// pattern compiles and caches a schema regex. ECMA-262 patterns mostly
+// work as RE2; ones that don't are skipped rather than failing every
+// response.
+func (v *schemaValidator) pattern(expr string) *regexp.Regexp {
+	if cached, ok := v.patterns.Load(expr); ok {
+		re, _ := cached.(*regexp.Regexp)
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		v.patterns.Store(expr, (*regexp.Regexp)(nil))
+		return nil
+	}
+	v.patterns.Store(expr, re)
+	return re
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validFormat checks the common string formats. Unknown formats pass.
+func validFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "uri", "url":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	}
+	return true
+}
+
+func schemaList(value any) []any {
+	list, _ := value.([]any)
+	return list
+}
+
+func schemaTypes(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		types := make([]string, 0, len(value))
+		for _, t := range value {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+func jsonEqual(a, b any) bool {
+	return reflect.DeepEqual(normalizeYAML(a), normalizeYAML(b))
+}
+
+func compactJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > 60 {
+		return string(data[:57]) + "..."
+	}
+	return string(data)
+}
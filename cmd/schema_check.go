@@ -0,0 +1,127 @@
+// cmd/schema_check.go
+//
+// --schema checks response bodies against a JSON Schema file, for a
+// whole command or, in suites, per line with schema=. Violations name the
+// JSON pointer of the offending value and are counted in the summaries
+// next to --validate-against's.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaFlags struct {
+	Path string
+}
+
+// schemaValidation validates every --schema and schema= file, sharing
+// their parsed documents and patterns.
+var schemaValidation = newSchemaValidator()
+
+func init() {
+	for _, cmd := range []*cobra.Command{customGetCmd, postCmd, executeCmd, stressCmd} {
+		cmd.Flags().StringVar(&schemaFlags.Path, "schema", "", "JSON Schema file every response body must match")
+	}
+}
+
+type jsonSchema struct {
+	name string
+	doc  *schemaDoc
+}
+
+func loadJSONSchema(path string) (*jsonSchema, error) {
+	doc, err := schemaValidation.loadDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	switch doc.root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("%s is not a JSON Schema", path)
+	}
+	return &jsonSchema{name: filepath.Base(path), doc: doc}, nil
+}
+
+// commandSchema loads the --schema file, or returns nil without one.
+func commandSchema() (*jsonSchema, error) {
+	if schemaFlags.Path == "" {
+		return nil, nil
+	}
+	return loadJSONSchema(schemaFlags.Path)
+}
+
+// lineSchema loads the schema= of a suite line, relative to the suite.
+func lineSchema(suiteFile, schema string) (*jsonSchema, error) {
+	if !filepath.IsAbs(schema) {
+		schema = filepath.Join(filepath.Dir(suiteFile), schema)
+	}
+	return loadJSONSchema(schema)
+}
+
+func (s *jsonSchema) check(body []byte) []string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("schema %s: body is not valid JSON: %v", s.name, err)}
+	}
+	var violations []string
+	for _, violation := range schemaValidation.validate(s.doc, s.doc.root, value) {
+		violations = append(violations, "schema "+s.name+": "+violation.String())
+	}
+	return violations
+}
+
+// finishResponse drains and closes the body of measured, checking it
+// first against the contract and schema when either is set.
+func finishResponse(req *http.Request, measured *measuredResponse, schema *jsonSchema) {
+	resp := measured.Res
+	if contract == nil && schema == nil {
+		drainAndClose(resp.Body)
+		return
+	}
+	measured.Validated = true
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		measured.Violations = append(measured.Violations, "reading body: "+err.Error())
+		return
+	}
+	if contract != nil {
+		if resp.Request != nil {
+			req = resp.Request
+		}
+		measured.Violations = append(measured.Violations, contract.check(req, resp, body)...)
+	}
+	if schema != nil {
+		measured.Violations = append(measured.Violations, schema.check(body)...)
+	}
+}
+
+func printViolations(measured measuredResponse) {
+	for _, violation := range measured.Violations {
+		fmt.Println("Violation:", violation)
+	}
+}
+
+// reportSchema checks the body of a single request command and exits
+// with status 1 on violations. They go to stderr so --body-only output
+// stays clean.
+func reportSchema(schema *jsonSchema, body []byte) {
+	if schema == nil {
+		return
+	}
+	violations := schema.check(body)
+	for _, violation := range violations {
+		fmt.Fprintln(os.Stderr, maskSecrets("Violation: "+violation))
+	}
+	if len(violations) > 0 {
+		exit(1)
+	}
+}
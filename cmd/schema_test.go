@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(`
+type: object
+required: [name]
+properties:
+  name: {type: string, minLength: 1}
+`), 0644)
+
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{"type", `{"type": "integer"}`, `1.5`, []string{"#: expected integer, got number"}},
+		{"number accepts integers", `{"type": "number"}`, `3`, nil},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, nil},
+		{"enum", `{"enum": ["a", "b"]}`, `"c"`, []string{`#: "c" is not one of the allowed values`}},
+		{"required and additional",
+			`{"required": ["id"], "properties": {"id": {}}, "additionalProperties": false}`, `{"x": 1}`,
+			[]string{`#: missing required property "id"`, "#/x: property is not allowed"}},
+		{"pointer escaping", `{"properties": {"a/b": {"type": "string"}}}`, `{"a/b": 1}`,
+			[]string{"#/a~1b: expected string, got integer"}},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}], "items": {"type": "integer"}}`, `["a", 1, "b"]`,
+			[]string{"#/2: expected integer, got string"}},
+		{"tuple items", `{"items": [{"type": "string"}], "additionalItems": false}`, `["a", 1]`,
+			[]string{"#/1: no value is allowed here"}},
+		{"uniqueItems", `{"uniqueItems": true}`, `[{"a": 1}, {"a": 1}]`,
+			[]string{"#: items 0 and 1 are equal, items must be unique"}},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1`,
+			[]string{"#: matches 2 of the oneOf schemas, expected exactly 1"}},
+		{"exclusiveMinimum boolean", `{"minimum": 1, "exclusiveMinimum": true}`, `1`, []string{"#: 1 is not greater than 1"}},
+		{"exclusiveMinimum number", `{"exclusiveMinimum": 1}`, `1`, []string{"#: 1 is not greater than 1"}},
+		{"format", `{"format": "email"}`, `"not an email"`, []string{`#: "not an email" is not a valid email`}},
+		{"external ref", `{"items": {"$ref": "pet.yaml"}}`, `[{"name": ""}, {}]`,
+			[]string{"#/0/name: length 0 is shorter than 1", `#/1: missing required property "name"`}},
+		{"missing ref", `{"$ref": "#/$defs/none"}`, `1`, []string{"#: $ref #/$defs/none not found"}},
+		{"circular ref", `{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, `1`,
+			[]string{"#: schema nests too deeply (circular $ref?)"}},
+		{"ref siblings apply",
+			`{"$defs": {"base": {"type": "object"}}, "$ref": "#/$defs/base", "required": ["id"]}`, `{}`,
+			[]string{`#: missing required property "id"`}},
+		{"ref siblings apply in 2020-12",
+			`{"$schema": "https://json-schema.org/draft/2020-12/schema", "$defs": {"base": {"type": "object"}}, "$ref": "#/$defs/base", "required": ["id"]}`, `{}`,
+			[]string{`#: missing required property "id"`}},
+		{"ref siblings ignored in draft-07",
+			`{"$schema": "http://json-schema.org/draft-07/schema#", "definitions": {"base": {"type": "object"}}, "$ref": "#/definitions/base", "required": ["id"]}`, `{}`,
+			nil},
+		{"ref siblings ignored in OpenAPI 3.0",
+			`{"openapi": "3.0.3", "components": {"schemas": {"base": {"type": "object"}}}, "$ref": "#/components/schemas/base", "required": ["id"]}`, `{}`,
+			nil},
+		{"ref siblings apply in OpenAPI 3.1",
+			`{"openapi": "3.1.0", "components": {"schemas": {"base": {"type": "object"}}}, "$ref": "#/components/schemas/base", "required": ["id"]}`, `{}`,
+			[]string{`#: missing required property "id"`}},
+	}
+	validator := newSchemaValidator()
+	for i, tt := range tests {
+		path := filepath.Join(dir, "schema"+strconv.Itoa(i)+".json")
+		os.WriteFile(path, []byte(tt.schema), 0644)
+		doc, err := validator.loadDocument(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, violation := range validator.validate(doc, doc.root, value) {
+			got = append(got, violation.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// cmd/secret.go
+//
+// Manages the encrypted secret store used by {{secret "name"}}. The store
+// is unlocked with $HPGO_SECRET_KEY.
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manages secrets in the encrypted store",
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set [name] [value]",
+	Short: "Stores a secret, reading the value from stdin if it's not given",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Println("Error reading secret from stdin:", err)
+				return
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Println("Error opening secret store:", err)
+			return
+		}
+		store.values[name] = value
+		if err := store.save(); err != nil {
+			fmt.Println("Error saving secret store:", err)
+			return
+		}
+		fmt.Println("Secret stored:", name)
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the names of stored secrets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Println("Error opening secret store:", err)
+			return
+		}
+		for _, name := range store.names() {
+			fmt.Println(name)
+		}
+	},
+}
+
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Removes a secret from the store",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSecretStore()
+		if err != nil {
+			fmt.Println("Error opening secret store:", err)
+			return
+		}
+		if _, ok := store.values[args[0]]; !ok {
+			fmt.Println("Secret does not exist:", args[0])
+			return
+		}
+		delete(store.values, args[0])
+		if err := store.save(); err != nil {
+			fmt.Println("Error saving secret store:", err)
+			return
+		}
+		fmt.Println("Secret deleted:", args[0])
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd, secretListCmd, secretDeleteCmd)
+	rootCmd.AddCommand(secretCmd)
+}
@@ -0,0 +1,384 @@
+// cmd/secrets.go
+//
+// Interpolation for suite lines and config values. ${NAME} expands to
+// an environment variable (or one from a .env file), {{secret "name"}}
+// to a secret looked up in the environment, then .env, then the local
+// encrypted store. Secret values are masked in everything printed to
+// stdout and stderr and in exported traces.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	envPattern    = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	secretPattern = regexp.MustCompile(`\{\{\s*secret\s+"([^"]+)"\s*\}\}`)
+)
+
+// interpolate expands ${NAME} and {{secret "name"}} in s.
+func interpolate(s string) (string, error) {
+	var firstErr error
+	s = envPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		value, ok := lookupVariable(name)
+		if !ok && firstErr == nil {
+			firstErr = fmt.Errorf("undefined variable ${%s}", name)
+		}
+		return value
+	})
+	s = secretPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := secretPattern.FindStringSubmatch(match)[1]
+		value, err := lookupSecret(name)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return value
+	})
+	return s, firstErr
+}
+
+func lookupVariable(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := dotEnv()[name]
+	return value, ok
+}
+
+func lookupSecret(name string) (string, error) {
+	value, ok := lookupVariable(name)
+	if !ok {
+		store, err := openSecretStore()
+		if err != nil {
+			return "", fmt.Errorf("secret %q: %w", name, err)
+		}
+		if value, ok = store.values[name]; !ok {
+			return "", fmt.Errorf("secret %q not found in the environment, .env or the secret store", name)
+		}
+	}
+	registerSecret(value)
+	return value, nil
+}
+
+// dotEnv returns the variables from .env files, read once.
+var dotEnv = sync.OnceValue(func() map[string]string {
+	values := make(map[string]string)
+	var files []string
+	if cwd, err := os.Getwd(); err == nil {
+		// The project root (next to .hpgo) first, so ./.env overrides it.
+		if dir, ok := findUp(cwd, ".hpgo"); ok {
+			files = append(files, filepath.Join(filepath.Dir(dir), ".env"))
+		}
+		files = append(files, filepath.Join(cwd, ".env"))
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			values[strings.TrimSpace(key)] = value
+		}
+		f.Close()
+	}
+	return values
+})
+
+// secretStore is a JSON map of secrets encrypted with AES-256-GCM under
+// a key derived (scrypt) from $HPGO_SECRET_KEY.
+type secretStore struct {
+	path   string
+	salt   []byte
+	values map[string]string
+}
+
+type secretStoreFile struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func secretStorePath() (string, error) {
+	if path := os.Getenv("HPGO_SECRETS_FILE"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "hpgo", "secrets.enc"), nil
+}
+
+func secretKey(salt []byte) (cipher.AEAD, error) {
+	passphrase := os.Getenv("HPGO_SECRET_KEY")
+	if passphrase == "" {
+		return nil, fmt.Errorf("set HPGO_SECRET_KEY to unlock the secret store")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var openedStore *secretStore
+
+// openSecretStore decrypts the store, or returns an empty one if there's
+// no store yet.
+func openSecretStore() (*secretStore, error) {
+	if openedStore != nil {
+		return openedStore, nil
+	}
+	path, err := secretStorePath()
+	if err != nil {
+		return nil, err
+	}
+	store := &secretStore{path: path, values: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file secretStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	aead, err := secretKey(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't decrypt %s: wrong HPGO_SECRET_KEY?", path)
+	}
+	if err := json.Unmarshal(plaintext, &store.values); err != nil {
+		return nil, err
+	}
+	store.salt = file.Salt
+	openedStore = store
+	return store, nil
+}
+
+func (s *secretStore) save() error {
+	if s.salt == nil {
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return err
+		}
+	}
+	aead, err := secretKey(s.salt)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(secretStoreFile{Salt: s.salt, Nonce: nonce, Ciphertext: aead.Seal(nil, nonce, plaintext, nil)})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func (s *secretStore) names() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const secretMask = "****"
+
+// masking filters stdout and stderr through pipes once the first secret
+// is used.
+var masking struct {
+	sync.Mutex
+	secrets []string
+	stdout  *maskedFile
+	stderr  *maskedFile
+}
+
+// maskedFile is stdout or stderr while it's being filtered.
+type maskedFile struct {
+	real *os.File
+	pipe *os.File
+	done chan struct{}
+}
+
+// partialFlushDelay is how long the end of an unfinished line, like a
+// prompt or a progress bar, is held back in case the rest of a secret
+// follows.
+const partialFlushDelay = 50 * time.Millisecond
+
+// registerSecret masks value from now on. Very short values are left
+// alone, masking them would garble unrelated output.
+func registerSecret(value string) {
+	if len(value) < 3 {
+		return
+	}
+	masking.Lock()
+	defer masking.Unlock()
+	for _, secret := range masking.secrets {
+		if secret == value {
+			return
+		}
+	}
+	masking.secrets = append(masking.secrets, value)
+	// Longest first, so a secret containing another is masked whole.
+	sort.Slice(masking.secrets, func(i, j int) bool { return len(masking.secrets[i]) > len(masking.secrets[j]) })
+
+	if masking.stdout == nil {
+		masking.stdout = maskFile(&os.Stdout)
+		masking.stderr = maskFile(&os.Stderr)
+	}
+}
+
+// maskFile swaps *f for a pipe whose output reaches the real file with
+// secrets masked, a line at a time.
+func maskFile(f **os.File) *maskedFile {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil
+	}
+	m := &maskedFile{real: *f, pipe: w, done: make(chan struct{})}
+	*f = w
+	go m.copy(r)
+	return m
+}
+
+func (m *maskedFile) copy(r *os.File) {
+	defer close(m.done)
+	var pending []byte
+	buf := make([]byte, 32*1024)
+	for {
+		// Without deadline support (some platforms' pipes) partial lines
+		// simply wait for their newline.
+		if len(pending) > 0 {
+			r.SetReadDeadline(time.Now().Add(partialFlushDelay))
+		} else {
+			r.SetReadDeadline(time.Time{})
+		}
+		n, err := r.Read(buf)
+		pending = append(pending, buf[:n]...)
+		if end := bytes.LastIndexByte(pending, '\n'); end >= 0 {
+			m.real.WriteString(maskSecrets(string(pending[:end+1])))
+			pending = append(pending[:0], pending[end+1:]...)
+		}
+		if err != nil || n == 0 {
+			if len(pending) > 0 {
+				m.real.WriteString(maskSecrets(string(pending)))
+				pending = pending[:0]
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return
+		}
+	}
+}
+
+// stop restores the real file and waits for the pipe to drain.
+func (m *maskedFile) stop(f **os.File) {
+	if m == nil {
+		return
+	}
+	*f = m.real
+	m.pipe.Close()
+	<-m.done
+}
+
+// realStdout is stdout itself, even while it's being filtered.
+func realStdout() *os.File {
+	masking.Lock()
+	defer masking.Unlock()
+	if masking.stdout != nil {
+		return masking.stdout.real
+	}
+	return os.Stdout
+}
+
+// realStderr is stderr itself, even while it's being filtered.
+func realStderr() *os.File {
+	masking.Lock()
+	defer masking.Unlock()
+	if masking.stderr != nil {
+		return masking.stderr.real
+	}
+	return os.Stderr
+}
+
+func maskSecrets(s string) string {
+	masking.Lock()
+	defer masking.Unlock()
+	for _, secret := range masking.secrets {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+	return s
+}
+
+// stopMasking flushes the filters and restores stdout and stderr.
+func stopMasking() {
+	masking.Lock()
+	stdout, stderr := masking.stdout, masking.stderr
+	masking.stdout, masking.stderr = nil, nil
+	masking.Unlock()
+	stdout.stop(&os.Stdout)
+	stderr.stop(&os.Stderr)
+}
+
+// exit saves cookie jars, exports spans and flushes masked output before
+// exiting.
+func exit(code int) {
+	saveCookieJars()
+	flushSpans()
+	stopMasking()
+	os.Exit(code)
+}
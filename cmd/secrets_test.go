@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("HPGO_TEST_HOST", "api.example.com")
+	t.Setenv("HPGO_TEST_TOKEN", "s3cret-token")
+	tests := []struct{ in, want, err string }{
+		{"https://${HPGO_TEST_HOST}/x", "https://api.example.com/x", ""},
+		{`Bearer {{secret "HPGO_TEST_TOKEN"}}`, "Bearer s3cret-token", ""},
+		{`{{ secret "HPGO_TEST_TOKEN" }}`, "s3cret-token", ""},
+		{"$HPGO_TEST_HOST ${not valid}", "$HPGO_TEST_HOST ${not valid}", ""},
+		{"${HPGO_TEST_UNDEFINED}", "", "undefined variable ${HPGO_TEST_UNDEFINED}"},
+	}
+	for _, tt := range tests {
+		got, err := interpolate(tt.in)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: got error %v, want %s", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMaskedFile(t *testing.T) {
+	masking.Lock()
+	saved := masking.secrets
+	masking.secrets = []string{"hunter2-long", "hunter2"}
+	masking.Unlock()
+	defer func() {
+		masking.Lock()
+		masking.secrets = saved
+		masking.Unlock()
+	}()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	f := w
+	m := maskFile(&f)
+
+	// A prompt without a newline still shows up.
+	io.WriteString(f, "password is hunter2? (y/n): ")
+	prompt := make([]byte, 100)
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := r.Read(prompt)
+	if err != nil || string(prompt[:n]) != "password is ****? (y/n): " {
+		t.Errorf("prompt: got %q, %v", prompt[:n], err)
+	}
+
+	// A secret split over two writes is still masked.
+	io.WriteString(f, "token hunt")
+	io.WriteString(f, "er2-long\nbye")
+	m.stop(&f)
+	w.Close()
+	rest, _ := io.ReadAll(r)
+	if got := string(rest); got != "token ****\nbye" {
+		t.Errorf("rest: got %q", got)
+	}
+	if f != w {
+		t.Error("stop didn't restore the file")
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	masking.Lock()
+	saved := masking.secrets
+	masking.secrets = []string{"abcdef", "abc"}
+	masking.Unlock()
+	defer func() {
+		masking.Lock()
+		masking.secrets = saved
+		masking.Unlock()
+	}()
+	if got := maskSecrets("abcdef abc ab"); got != strings.Join([]string{secretMask, secretMask, "ab"}, " ") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,395 @@
+// cmd/serve.go
+//
+// 'serve' runs a local mock server from a YAML file of routes, so suites
+// can be developed without the real service and hpgo's numbers checked
+// against latency that is known in advance:
+//
+//	listen: 127.0.0.1:8080
+//	routes:
+//	  - method: GET
+//	    path: /users/{id}
+//	    headers:
+//	      Content-Type: application/json
+//	    body: '{"id": "{id}"}'
+//	    latency: {distribution: normal, mean: 50ms, stddev: 10ms}
+//	    error_rate: 0.05
+//	    error_status: 503
+//	  - path: /echo/
+//	    echo: true
+//	  - path: /download
+//	    body_file: big.bin
+//	    slow_body: {chunk: 1024, interval: 100ms}
+//
+// Paths are http.ServeMux patterns; {name} in a body is replaced by the
+// path value of that name. latency is a duration, or a distribution:
+// fixed (value), uniform (min, max), normal (mean, stddev) or
+// exponential (mean), each optionally clamped to min and max.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var serveFlags struct {
+	Listen string
+	Quiet  bool
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveFlags.Listen, "listen", "", "Address to listen on (default the file's listen, or 127.0.0.1:8080)")
+	serveCmd.Flags().BoolVar(&serveFlags.Quiet, "quiet", false, "Don't log each request")
+	rootCmd.AddCommand(serveCmd)
+}
+
+type latency struct {
+	Distribution string        `yaml:"distribution"`
+	Value        time.Duration `yaml:"value"`
+	Mean         time.Duration `yaml:"mean"`
+	StdDev       time.Duration `yaml:"stddev"`
+	Min          time.Duration `yaml:"min"`
+	Max          time.Duration `yaml:"max"`
+}
+
+// UnmarshalYAML accepts a plain duration as a fixed latency.
+func (l *latency) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		value, err := time.ParseDuration(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: latency: %w", node.Line, err)
+		}
+		*l = latency{Distribution: "fixed", Value: value}
+		return nil
+	}
+	type plain latency
+	return node.Decode((*plain)(l))
+}
+
+func (l *latency) validate() error {
+	switch l.Distribution {
+	case "", "fixed":
+		if l.Value == 0 {
+			l.Value = l.Mean
+		}
+	case "uniform":
+		if l.Max < l.Min {
+			return fmt.Errorf("uniform latency needs min <= max")
+		}
+	case "normal", "exponential":
+		if l.Mean <= 0 {
+			return fmt.Errorf("%s latency needs a mean", l.Distribution)
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q (want fixed, uniform, normal or exponential)", l.Distribution)
+	}
+	return nil
+}
+
+// sample draws one delay.
+func (l *latency) sample() time.Duration {
+	var d time.Duration
+	switch l.Distribution {
+	case "", "fixed":
+		return l.Value
+	case "uniform":
+		d = l.Min + time.Duration(rand.Int64N(int64(l.Max-l.Min)+1))
+	case "normal":
+		d = l.Mean + time.Duration(rand.NormFloat64()*float64(l.StdDev))
+	case "exponential":
+		d = time.Duration(rand.ExpFloat64() * float64(l.Mean))
+	}
+	if d < l.Min {
+		d = l.Min
+	}
+	if l.Max > 0 && d > l.Max {
+		d = l.Max
+	}
+	return max(d, 0)
+}
+
+type slowBody struct {
+	Chunk    int           `yaml:"chunk"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+type mockRoute struct {
+	Method      string            `yaml:"method"`
+	Path        string            `yaml:"path"`
+	Status      int               `yaml:"status"`
+	Headers     map[string]string `yaml:"headers"`
+	Body        string            `yaml:"body"`
+	BodyFile    string            `yaml:"body_file"`
+	Echo        bool              `yaml:"echo"`
+	Latency     *latency          `yaml:"latency"`
+	ErrorRate   float64           `yaml:"error_rate"`
+	ErrorStatus int               `yaml:"error_status"`
+	ErrorBody   string            `yaml:"error_body"`
+	SlowBody    *slowBody         `yaml:"slow_body"`
+
+	wildcards []string
+}
+
+type mockConfig struct {
+	Listen string      `yaml:"listen"`
+	Routes []mockRoute `yaml:"routes"`
+}
+
+var wildcardPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\.\.\.)?\}`)
+
+func loadMockConfig(path string) (*mockConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config mockConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(config.Routes) == 0 {
+		return nil, fmt.Errorf("%s: no routes", path)
+	}
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		name := fmt.Sprintf("route %d (%s)", i+1, strings.TrimSpace(route.Method+" "+route.Path))
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("%s: path must start with /", name)
+		}
+		if route.Status == 0 {
+			route.Status = http.StatusOK
+		}
+		if route.ErrorStatus == 0 {
+			route.ErrorStatus = http.StatusInternalServerError
+		}
+		if route.ErrorRate < 0 || route.ErrorRate > 1 {
+			return nil, fmt.Errorf("%s: error_rate must be between 0 and 1", name)
+		}
+		if route.Latency != nil {
+			if err := route.Latency.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		if route.SlowBody != nil && (route.SlowBody.Chunk <= 0 || route.SlowBody.Interval <= 0) {
+			return nil, fmt.Errorf("%s: slow_body needs a chunk size and an interval", name)
+		}
+		if route.BodyFile != "" {
+			file := route.BodyFile
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(path), file)
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			route.Body = string(content)
+		}
+		for _, match := range wildcardPattern.FindAllStringSubmatch(route.Path, -1) {
+			route.wildcards = append(route.wildcards, match[1])
+		}
+	}
+	return &config, nil
+}
+
+// echoResponse is what an echo route sends back.
+type echoResponse struct {
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Query      map[string][]string `json:"query"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+	RemoteAddr string              `json:"remote_addr"`
+	Proto      string              `json:"proto"`
+}
+
+// statusWriter remembers the status for the request log.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (route *mockRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if route.Latency != nil {
+		select {
+		case <-time.After(route.Latency.sample()):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for key, value := range route.Headers {
+		w.Header().Set(key, value)
+	}
+	if route.ErrorRate > 0 && rand.Float64() < route.ErrorRate {
+		body := route.ErrorBody
+		if body == "" {
+			body = http.StatusText(route.ErrorStatus) + "\n"
+		}
+		w.WriteHeader(route.ErrorStatus)
+		io.WriteString(w, body)
+		return
+	}
+
+	body := route.Body
+	if route.Echo {
+		requestBody, _ := io.ReadAll(r.Body)
+		data, err := json.MarshalIndent(echoResponse{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.Query(),
+			Headers:    r.Header,
+			Body:       string(requestBody),
+			RemoteAddr: r.RemoteAddr,
+			Proto:      r.Proto,
+		}, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = string(data) + "\n"
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+	}
+	for _, name := range route.wildcards {
+		body = strings.ReplaceAll(body, "{"+name+"}", r.PathValue(name))
+	}
+
+	if route.SlowBody == nil {
+		w.WriteHeader(route.Status)
+		io.WriteString(w, body)
+		return
+	}
+	// Announce the full length so clients can show progress.
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	w.WriteHeader(route.Status)
+	controller := http.NewResponseController(w)
+	for len(body) > 0 {
+		n := min(route.SlowBody.Chunk, len(body))
+		if _, err := io.WriteString(w, body[:n]); err != nil {
+			return
+		}
+		controller.Flush()
+		body = body[n:]
+		if len(body) == 0 {
+			break
+		}
+		select {
+		case <-time.After(route.SlowBody.Interval):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// newMockHandler registers the routes on a ServeMux, turning its panics
+// on bad or conflicting patterns into errors.
+func newMockHandler(config *mockConfig, served *atomic.Int64) (handler http.Handler, err error) {
+	mux := http.NewServeMux()
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		pattern := route.Path
+		if route.Method != "" {
+			pattern = strings.ToUpper(route.Method) + " " + route.Path
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("route %d: %v", i+1, r)
+				}
+			}()
+			mux.Handle(pattern, route)
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(sw, r)
+		served.Add(1)
+		if !serveFlags.Quiet {
+			outputMu.Lock()
+			fmt.Printf("%s %s %s %d %v\n", start.Format("15:04:05.000"), r.Method, r.URL.RequestURI(), sw.status, time.Since(start).Round(time.Microsecond))
+			outputMu.Unlock()
+		}
+	}), nil
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [routes.yaml]",
+	Short: "Runs a local mock server with the routes from a YAML file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := loadMockConfig(args[0])
+		if err != nil {
+			fmt.Println("Error reading routes:", err)
+			exit(1)
+		}
+		var served atomic.Int64
+		handler, err := newMockHandler(config, &served)
+		if err != nil {
+			fmt.Println("Error registering routes:", err)
+			exit(1)
+		}
+
+		addr := serveFlags.Listen
+		if addr == "" {
+			addr = config.Listen
+		}
+		if addr == "" {
+			addr = "127.0.0.1:8080"
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			fmt.Println("Error listening:", err)
+			exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		server := &http.Server{Handler: handler}
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			<-ctx.Done()
+			// Give requests in flight, slow bodies included, a moment to finish.
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		fmt.Printf("Serving %d routes on http://%s\n", len(config.Routes), listener.Addr())
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("Error serving:", err)
+			exit(1)
+		}
+		<-closed
+		fmt.Println("\nRequests served:", served.Load())
+	},
+}
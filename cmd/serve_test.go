@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLatency(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		err      string
+		min, max time.Duration
+		mean     time.Duration
+	}{
+		{yaml: "25ms", min: 25 * time.Millisecond, max: 25 * time.Millisecond, mean: 25 * time.Millisecond},
+		{yaml: "{mean: 10ms}", min: 10 * time.Millisecond, max: 10 * time.Millisecond, mean: 10 * time.Millisecond},
+		{yaml: "{distribution: uniform, min: 10ms, max: 30ms}", min: 10 * time.Millisecond, max: 30 * time.Millisecond, mean: 20 * time.Millisecond},
+		{yaml: "{distribution: normal, mean: 50ms, stddev: 10ms}", min: 0, max: time.Second, mean: 50 * time.Millisecond},
+		{yaml: "{distribution: normal, mean: 5ms, stddev: 50ms, max: 20ms}", min: 0, max: 20 * time.Millisecond},
+		{yaml: "{distribution: exponential, mean: 20ms}", min: 0, max: time.Minute, mean: 20 * time.Millisecond},
+		{yaml: "{distribution: exponential, mean: 20ms, min: 15ms, max: 25ms}", min: 15 * time.Millisecond, max: 25 * time.Millisecond},
+		{yaml: "soon", err: "latency: time: invalid duration"},
+		{yaml: "{distribution: uniform, min: 30ms, max: 10ms}", err: "uniform latency needs min <= max"},
+		{yaml: "{distribution: normal, stddev: 10ms}", err: "normal latency needs a mean"},
+		{yaml: "{distribution: pareto}", err: "unknown latency distribution"},
+	}
+	for _, tt := range tests {
+		var l latency
+		err := yaml.Unmarshal([]byte(tt.yaml), &l)
+		if err == nil {
+			err = l.validate()
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.yaml, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.yaml, err)
+			continue
+		}
+
+		const n = 5000
+		var sum time.Duration
+		for i := 0; i < n; i++ {
+			d := l.sample()
+			if d < tt.min || d > tt.max {
+				t.Errorf("%s: sampled %v, outside [%v, %v]", tt.yaml, d, tt.min, tt.max)
+				break
+			}
+			sum += d
+		}
+		if mean := sum / n; tt.mean > 0 && (mean < tt.mean*9/10 || mean > tt.mean*11/10) {
+			t.Errorf("%s: mean %v, want about %v", tt.yaml, mean, tt.mean)
+		}
+	}
+}
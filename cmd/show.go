@@ -0,0 +1,68 @@
+// cmd/show.go
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxSuiteLine bounds one suite line. record and the importers put whole
+// request bodies on a line, so it's well past bufio's 64 KiB default.
+const maxSuiteLine = 64 << 20
+
+// readFileLines returns every line of a suite file, blank ones
+// included so line numbers match what an editor shows.
+func readFileLines(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var fileLines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxSuiteLine)
+	for scanner.Scan() {
+		fileLines = append(fileLines, scanner.Text())
+	}
+	return fileLines, scanner.Err()
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show [fileName]",
+	Short: "Prints a txt file with line numbers",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName := args[0]
+		if !strings.HasSuffix(fileName, ".txt") {
+			fileName = fileName + ".txt"
+		}
+
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fileLines, err := readFileLines(filePath)
+		if os.IsNotExist(err) {
+			fmt.Println("File does not exist:", fileName)
+			return
+		}
+		if err != nil {
+			fmt.Println("Error reading file:", err)
+			return
+		}
+		for i, line := range fileLines {
+			fmt.Printf("%4d  %s\n", i+1, line)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
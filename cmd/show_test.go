@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// record and the importers write whole bodies on one line.
+func TestReadFileLinesLongLine(t *testing.T) {
+	body := strings.Repeat(`{"key":"value"},`, 20000)
+	long := formatLine(lines{URL: "http://example.com", NumTimes: 1, Method: "POST", Body: body})
+	file := filepath.Join(t.TempDir(), "suite.txt")
+	os.WriteFile(file, []byte("http://example.com 1\n"+long+"\n\nhttp://example.com/last 2\n"), 0644)
+
+	fileLines, err := readFileLines(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileLines) != 4 || fileLines[1] != long || fileLines[3] != "http://example.com/last 2" {
+		t.Fatalf("got %d lines", len(fileLines))
+	}
+	if line, err := parseLine(fileLines[1]); err != nil || line.Body != body {
+		t.Errorf("long line: %v", err)
+	}
+}
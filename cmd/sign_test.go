@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credentials and date of the AWS SigV4 test suite.
+var testSigV4 = &sigV4Signer{
+	region:    "us-east-1",
+	service:   "service",
+	accessKey: "AKIDEXAMPLE",
+	secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+var testSigV4Date = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestSigV4TestSuite(t *testing.T) {
+	tests := []struct {
+		name          string
+		method, url   string
+		contentType   string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{"get-vanilla", "GET", "https://example.amazonaws.com/", "", "", "host;x-amz-date",
+			"5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-empty-query-key", "GET", "https://example.amazonaws.com/?Param1=value1", "", "", "host;x-amz-date",
+			"a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb"},
+		{"get-vanilla-query-order-key-case", "GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "", "", "host;x-amz-date",
+			"b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-query-unreserved", "GET",
+			"https://example.amazonaws.com/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+			"", "", "host;x-amz-date",
+			"9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197"},
+		{"get-unreserved", "GET", "https://example.amazonaws.com/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+			"", "", "host;x-amz-date",
+			"07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{"post-vanilla", "POST", "https://example.amazonaws.com/", "", "", "host;x-amz-date",
+			"5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-x-www-form-urlencoded", "POST", "https://example.amazonaws.com/", "application/x-www-form-urlencoded", "Param1=value1",
+			"content-type;host;x-amz-date",
+			"ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		if err := testSigV4.sign(req, []byte(tt.body), testSigV4Date); err != nil {
+			t.Fatal(err)
+		}
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+			tt.signedHeaders + ", Signature=" + tt.signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct{ query, want string }{
+		{"a=1&a-b=1", "a=1&a-b=1"},
+		{"b=2&a=2&a=1", "a=1&a=2&b=2"},
+		{"q=a b&x=%2F", "q=a%20b&x=%2F"},
+		{"k=", "k="},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "https://example.com/?"+tt.query, nil)
+		if got := canonicalQuery(req); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalURI(t *testing.T) {
+	tests := []struct{ service, url, want string }{
+		{"service", "https://example.com", "/"},
+		{"service", "https://example.com/a/b", "/a/b"},
+		{"service", "https://example.com/a b/%E1%88%B4", "/a%2520b/%25E1%2588%25B4"},
+		// Go sends sub-delims like these unescaped, so they're encoded once.
+		{"service", "https://example.com/a:b@c$d(e)=f,g", "/a%3Ab%40c%24d%28e%29%3Df%2Cg"},
+		{"service", "https://example.com/a%3Ab", "/a%253Ab"},
+		{"s3", "https://example.com/a b/%E1%88%B4", "/a%20b/%E1%88%B4"},
+		{"s3", "https://example.com/a:b@c$d(e)=f,g", "/a%3Ab%40c%24d%28e%29%3Df%2Cg"},
+		{"s3", "https://example.com/a%3Ab", "/a%3Ab"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		signer := &sigV4Signer{service: tt.service}
+		if got := signer.canonicalURI(req); got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.service, tt.url, got, tt.want)
+		}
+	}
+}
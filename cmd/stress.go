@@ -7,15 +7,13 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 	"math"
-	"net/http/httptrace"
 	"github.com/spf13/cobra"
 )
 
@@ -38,8 +36,151 @@ type record struct {
 	TotalDNSTimeRecorded	 time.Duration
 	TotalConnectTimeRecorded time.Duration
 	TotalTLSTimeRecorded 	 time.Duration
+	TotalQUICTimeRecorded	 time.Duration
+	QUICHandshakes			 int
+	TotalProxyConnectTimeRecorded time.Duration
 	TotalTimeRecorded 		 time.Duration
+	Count					 int
+	ReusedConns				 int
+	Redirects				 int
+	TLSVersions				 map[string]int
+	CipherSuites			 map[string]int
+	EarliestCertExpiry		 time.Time
 	Status					 map[string]int
+	Protocols				 map[string]int
+	TotalWireBytes			 int64
+	TotalDecodedBytes		 int64
+	Encodings				 map[string]int
+	Validated				 int
+	ViolatingResponses		 int
+	Violations				 map[string]int
+}
+
+// add folds a single response into the running totals.
+func (r *record) add(response measuredResponse) {
+	r.TotalDNSTimeRecorded += response.DNS
+	r.TotalConnectTimeRecorded += response.Connect
+	r.TotalTLSTimeRecorded += response.TLS
+	if response.QUIC > 0 {
+		r.TotalQUICTimeRecorded += response.QUIC
+		r.QUICHandshakes++
+	}
+	r.TotalProxyConnectTimeRecorded += response.ProxyConnect
+	r.Count++
+	if response.Reused {
+		r.ReusedConns++
+	}
+	r.Redirects += len(response.Redirects)
+	if response.TLSVersion != "" {
+		if r.TLSVersions == nil {
+			r.TLSVersions = make(map[string]int)
+			r.CipherSuites = make(map[string]int)
+		}
+		r.TLSVersions[response.TLSVersion]++
+		r.CipherSuites[response.CipherSuite]++
+	}
+	if !response.CertExpiry.IsZero() && (r.EarliestCertExpiry.IsZero() || response.CertExpiry.Before(r.EarliestCertExpiry)) {
+		r.EarliestCertExpiry = response.CertExpiry
+	}
+	if response.Body != nil {
+		if r.Encodings == nil {
+			r.Encodings = make(map[string]int)
+		}
+		r.Encodings[response.Body.Encoding]++
+		r.TotalWireBytes += response.Body.Wire()
+		r.TotalDecodedBytes += response.Body.Decoded()
+	}
+	if response.Validated {
+		r.Validated++
+	}
+	if len(response.Violations) > 0 {
+		if r.Violations == nil {
+			r.Violations = make(map[string]int)
+		}
+		r.ViolatingResponses++
+		for _, violation := range response.Violations {
+			r.Violations[violation]++
+		}
+	}
+	r.TotalTimeRecorded += response.TotalTime
+	r.Status[response.Status]++
+	if r.Protocols == nil {
+		r.Protocols = make(map[string]int)
+	}
+	r.Protocols[response.Proto]++
+	if r.Fastest > response.TotalTime {
+		r.Fastest = response.TotalTime
+	}
+	if r.Slowest < response.TotalTime {
+		r.Slowest = response.TotalTime
+	}
+}
+
+// printRecordBreakdown prints the per-response breakdowns that follow
+// the status results in every summary.
+func printRecordBreakdown(r record) {
+	if r.QUICHandshakes > 0 {
+		// QUIC connects and does its TLS handshake in one step.
+		fmt.Println("Average QUIC Handshake Runtime:", r.TotalQUICTimeRecorded / time.Duration(r.QUICHandshakes))
+	}
+	if r.TotalProxyConnectTimeRecorded > 0 && r.Count > 0 {
+		fmt.Println("Average Proxy CONNECT Runtime:", r.TotalProxyConnectTimeRecorded / time.Duration(r.Count))
+	}
+	fmt.Println("Connections Reused:", r.ReusedConns)
+	if r.Redirects > 0 {
+		fmt.Println("Redirects Followed:", r.Redirects)
+	}
+	fmt.Println("Protocol Results: ")
+	for proto, count := range r.Protocols {
+		fmt.Printf("%s: %d\n", proto, count)
+	}
+	if len(r.TLSVersions) > 0 {
+		fmt.Println("TLS Version Results: ")
+		for version, count := range r.TLSVersions {
+			fmt.Printf("%s: %d\n", version, count)
+		}
+		fmt.Println("Cipher Suite Results: ")
+		for suite, count := range r.CipherSuites {
+			fmt.Printf("%s: %d\n", suite, count)
+		}
+	}
+	if !r.EarliestCertExpiry.IsZero() {
+		fmt.Println("Earliest Certificate Expiry:", r.EarliestCertExpiry.Format(time.RFC3339))
+	}
+	if r.TotalDecodedBytes > 0 {
+		fmt.Printf("Body Bytes: %s on the wire, %s decoded (%.1f%%)\n", formatBytes(r.TotalWireBytes),
+			formatBytes(r.TotalDecodedBytes), 100*float64(r.TotalWireBytes)/float64(r.TotalDecodedBytes))
+		fmt.Println("Content Encoding Results: ")
+		for encoding, count := range r.Encodings {
+			fmt.Printf("%s: %d\n", encoding, count)
+		}
+	}
+	if r.Validated > 0 {
+		fmt.Printf("Validation Failures: %d of %d responses\n", r.ViolatingResponses, r.Validated)
+		printViolationCounts(r.Violations)
+	}
+}
+
+// printViolationCounts prints the most frequent violations first.
+func printViolationCounts(violations map[string]int) {
+	messages := make([]string, 0, len(violations))
+	for message := range violations {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if violations[messages[i]] != violations[messages[j]] {
+			return violations[messages[i]] > violations[messages[j]]
+		}
+		return messages[i] < messages[j]
+	})
+	const shown = 10
+	for i, message := range messages {
+		if i == shown {
+			fmt.Printf("... and %d more\n", len(messages)-shown)
+			break
+		}
+		fmt.Printf("%s: %d\n", message, violations[message])
+	}
 }
 
 var stressCmd = &cobra.Command{
@@ -74,16 +215,41 @@ var stressCmd = &cobra.Command{
             }
         }
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-            url = "http://" + url
-        }
+		url, err = normalizeURL(url)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := loadContract(); err != nil {
+			fmt.Println("Error reading spec:", err)
+			return
+		}
+		schema, err := commandSchema()
+		if err != nil {
+			fmt.Println("Error reading schema:", err)
+			return
+		}
+		shared, err := newSharedTransport(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating transport:", err)
+			return
+		}
+		// Every worker is a virtual user with a session (cookie jar) of
+		// its own; requests are handed out to them in turn.
+		sessions := make([]http.RoundTripper, max(stressFlags.NumWorkers, 1))
+		for i := range sessions {
+			if sessions[i], err = transportFlags.sessionTransport(shared); err != nil {
+				fmt.Println("Error creating transport:", err)
+				return
+			}
+		}
 		var wg sync.WaitGroup
 
 		ch := make(chan measuredResponse, stressFlags.NumWorkers)
 
 		for i := 0; i < times; i++ {
 			wg.Add(1)
-			go getRequest(url, &wg, ch)
+			go getRequest(sessions[i%len(sessions)], url, schema, &wg, ch)
 		}
 		go func() {
 			wg.Wait()
@@ -91,17 +257,7 @@ var stressCmd = &cobra.Command{
 		}()
 	
 		for response := range ch {
-			result.TotalDNSTimeRecorded += response.DNS
-			result.TotalConnectTimeRecorded += response.Connect
-			result.TotalTLSTimeRecorded += response.TLS
-			result.TotalTimeRecorded += response.TotalTime
-			result.Status[response.Status]++
-			if result.Fastest > response.TotalTime {
-				result.Fastest = response.TotalTime
-			}
-			if result.Slowest < response.TotalTime {
-				result.Slowest = response.TotalTime
-			}
+			result.add(response)
 		}
 		
 		averageDNSTime := result.TotalDNSTimeRecorded / time.Duration(times)
@@ -113,8 +269,10 @@ var stressCmd = &cobra.Command{
 		fmt.Println("Method: 'GET'")
 		fmt.Println("Number of concurrent workers:", stressFlags.NumWorkers)
 		fmt.Println("Average DNS Runtime:", averageDNSTime)
-		fmt.Println("Average Connect Runtime:", averageConnectTime)
-		fmt.Println("Average TLS Runtime:", averageTLSTime)
+		if !transportFlags.HTTP3 {
+			fmt.Println("Average Connect Runtime:", averageConnectTime)
+			fmt.Println("Average TLS Runtime:", averageTLSTime)
+		}
 		fmt.Println("Average Total Runtime:", averageTime)
 		fmt.Println("Fastest Runtime: ", result.Fastest)
 		fmt.Println("Slowest Runtime: ", result.Slowest)
@@ -122,6 +280,11 @@ var stressCmd = &cobra.Command{
 		for status, count := range result.Status {
 			fmt.Printf("%s: %d\n", status, count)
 		}
+		printRecordBreakdown(result)
+		printConnectionStats()
+		if result.ViolatingResponses > 0 {
+			exit(1)
+		}
 	},
 }
 
@@ -131,51 +294,38 @@ type measuredResponse struct {
 	DNS       time.Duration
 	Connect   time.Duration
 	TLS       time.Duration
+	QUIC      time.Duration
 	TotalTime time.Duration
+	Reused    bool
+	TLSVersion  string
+	CipherSuite string
+	CertExpiry  time.Time
+	ProxyConnect time.Duration
+	Redirects   []redirectHop
+	Body        *bodySizes
+	Validated   bool
+	Violations  []string
+
+	proxyConnectStart time.Time
 	Status    string
+	Proto     string
 }
 
-func getRequest(url string, wg *sync.WaitGroup, ch chan <- measuredResponse) {
+func getRequest(transport http.RoundTripper, url string, schema *jsonSchema, wg *sync.WaitGroup, ch chan <- measuredResponse) {
 	defer wg.Done()
 	req, _ := http.NewRequest("GET", url, nil)
 
-	measured := measuredResponse{}
-	var start, connect, dns, tlsHandshake time.Time
-
-	trace := &httptrace.ClientTrace{
-		DNSStart: func(dsi httptrace.DNSStartInfo) { dns = time.Now() },
-		DNSDone: func(ddi httptrace.DNSDoneInfo) {
-			measured.DNS = time.Since(dns)
-		},
-		ConnectStart: func(network, addr string) { connect = time.Now() },
-		ConnectDone: func(network, addr string, err error) {
-			measured.Connect = time.Since(connect)
-		},
-		TLSHandshakeStart: func() { tlsHandshake = time.Now() },
-		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
-			measured.TLS = time.Since(tlsHandshake)
-		},
-
-		// From when the first byte is registered back
-		GotFirstResponseByte: func() {
-			measured.TotalTime = time.Since(start)
-		},
-	}
-
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-	start = time.Now()
-
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	measured, err := measureRequest(transport.RoundTrip, req)
 	if err != nil {
 		return
 	}
-	measured.Start = start
-	measured.Res = resp
-	measured.Status = resp.Status
-	defer resp.Body.Close()
+	resp := measured.Res
+	finishResponse(req, &measured, schema)
 	
 	if stressFlags.ShowSingleProcesses {
-		fmt.Printf("Status: %s\nTotal Time: %v\n\n", resp.Status, measured.TotalTime)
+		printRedirects(measured)
+		printViolations(measured)
+		fmt.Printf("Status: %s\nTotal Time: %v\nBody Size: %s\n\n", resp.Status, measured.TotalTime, bodySummary(measured))
 	}
 	ch <- measured
 }
\ No newline at end of file
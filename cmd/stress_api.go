@@ -6,15 +6,12 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 	"math"
-	"net/http/httptrace"
 	"github.com/spf13/cobra"
 	"io"
 )
@@ -32,16 +29,6 @@ func init() {
 	rootCmd.AddCommand(stressAPICmd)
 }
 
-func createHTTPClient() *http.Client {
-	tr := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-	}
-	return &http.Client{Transport: tr}
-}
-
-
 var stressAPICmd = &cobra.Command{
 	Use:   "stressa [url] [numTimes]",
 	Short: "Stress tests an api",
@@ -74,14 +61,20 @@ var stressAPICmd = &cobra.Command{
             }
         }
 
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-            url = "http://" + url
-        }
+		url, err = normalizeURL(url)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 		var wg sync.WaitGroup
 
 		ch := make(chan measuredResponse, stressFlags.NumWorkers)
 
-		client := createHTTPClient()
+		client, err := newClient(transportFlags)
+		if err != nil {
+			fmt.Println("Error creating client:", err)
+			return
+		}
 
 		for i := 0; i < times; i++ {
 			wg.Add(1)
@@ -93,17 +86,7 @@ var stressAPICmd = &cobra.Command{
 		}()
 	
 		for response := range ch {
-			result.TotalDNSTimeRecorded += response.DNS
-			result.TotalConnectTimeRecorded += response.Connect
-			result.TotalTLSTimeRecorded += response.TLS
-			result.TotalTimeRecorded += response.TotalTime
-			result.Status[response.Status]++
-			if result.Fastest > response.TotalTime {
-				result.Fastest = response.TotalTime
-			}
-			if result.Slowest < response.TotalTime {
-				result.Slowest = response.TotalTime
-			}
+			result.add(response)
 		}
 		
 		averageDNSTime := result.TotalDNSTimeRecorded / time.Duration(times)
@@ -115,8 +98,10 @@ var stressAPICmd = &cobra.Command{
 		fmt.Println("Method: 'GET'")
 		fmt.Println("Number of concurrent workers:", stressFlags.NumWorkers)
 		fmt.Println("Average DNS Runtime:", averageDNSTime)
-		fmt.Println("Average Connect Runtime:", averageConnectTime)
-		fmt.Println("Average TLS Runtime:", averageTLSTime)
+		if !transportFlags.HTTP3 {
+			fmt.Println("Average Connect Runtime:", averageConnectTime)
+			fmt.Println("Average TLS Runtime:", averageTLSTime)
+		}
 		fmt.Println("Average Total Runtime:", averageTime)
 		fmt.Println("Fastest Runtime: ", result.Fastest)
 		fmt.Println("Slowest Runtime: ", result.Slowest)
@@ -124,6 +109,10 @@ var stressAPICmd = &cobra.Command{
 		for status, count := range result.Status {
 			fmt.Printf("%s: %d\n", status, count)
 		}
+		printRecordBreakdown(result)
+		// Closed then counts the pooled connections too.
+		closeIdleConnections()
+		printConnectionStats()
 	},
 }
 func stressAPIRequest(client *http.Client, url string, wg *sync.WaitGroup, ch chan<- measuredResponse) {
@@ -135,43 +124,20 @@ func stressAPIRequest(client *http.Client, url string, wg *sync.WaitGroup, ch ch
         return
     }
 
-    measured := measuredResponse{}
-    var start, connect, dns, tlsHandshake time.Time
-
-    trace := &httptrace.ClientTrace{
-        DNSStart: func(dsi httptrace.DNSStartInfo) { dns = time.Now() },
-        DNSDone: func(ddi httptrace.DNSDoneInfo) {
-            measured.DNS = time.Since(dns)
-        },
-        ConnectStart: func(network, addr string) { connect = time.Now() },
-        ConnectDone: func(network, addr string, err error) {
-            measured.Connect = time.Since(connect)
-        },
-        TLSHandshakeStart: func() { tlsHandshake = time.Now() },
-        TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
-            measured.TLS = time.Since(tlsHandshake)
-        },
-        GotFirstResponseByte: func() {
-            measured.TotalTime = time.Since(start)
-        },
-    }
-
-    req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-    start = time.Now()
-
-    resp, err := client.Do(req)
+    measured, err := measureRequest(client.Do, req)
     if err != nil {
         fmt.Println("Error performing request:", err)
         return
     }
-    measured.Start = start
-    measured.Res = resp
-    measured.Status = resp.Status
-    defer resp.Body.Close()
+    resp := measured.Res
+    var body []byte
+    if stressAPIFlags.ShowSingleProcesses {
+        body, _ = io.ReadAll(resp.Body)
+    }
+    drainAndClose(resp.Body)
 
     if stressAPIFlags.ShowSingleProcesses {
-        fmt.Printf("Status: %s\nTotal Time: %v\n\n", resp.Status, measured.TotalTime)
-        body, _ := io.ReadAll(resp.Body)
+        fmt.Printf("Status: %s\nTotal Time: %v\nBody Size: %s\n\n", resp.Status, measured.TotalTime, bodySummary(measured))
         fmt.Println("Body: ", string(body))
     }
     ch <- measured
@@ -0,0 +1,128 @@
+// cmd/tls.go
+//
+// TLS options shared by every transport, and the summary of what was
+// actually negotiated.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type tlsOptions struct {
+	CACert     string
+	Cert       string
+	Key        string
+	Insecure   bool
+	ServerName string
+	MinVersion string
+	Ciphers    string
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.CACert, "cacert", "", "PEM file with CA certificates to trust")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.Cert, "cert", "", "PEM client certificate for mTLS")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.Key, "key", "", "PEM private key for --cert")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.TLS.Insecure, "insecure", false, "Skip server certificate verification")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.ServerName, "servername", "", "Override the SNI / verified server name")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.MinVersion, "tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	rootCmd.PersistentFlags().StringVar(&transportFlags.TLS.Ciphers, "ciphers", "", "Comma separated TLS 1.0-1.2 cipher suites to allow")
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == name {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// config builds the tls.Config for opts. It returns nil when nothing
+// was set so transports keep their defaults.
+func (opts tlsOptions) config() (*tls.Config, error) {
+	if opts == (tlsOptions{}) {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+		ServerName:         opts.ServerName,
+	}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading --cacert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.Cert != "" || opts.Key != "" {
+		if opts.Cert == "" || opts.Key == "" {
+			return nil, fmt.Errorf("--cert and --key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.MinVersion != "" {
+		version, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown --tls-min-version %q", opts.MinVersion)
+		}
+		cfg.MinVersion = version
+	}
+
+	if opts.Ciphers != "" {
+		for _, name := range strings.Split(opts.Ciphers, ",") {
+			id, ok := cipherSuiteID(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("unknown cipher suite %q", name)
+			}
+			cfg.CipherSuites = append(cfg.CipherSuites, id)
+		}
+	}
+	return cfg, nil
+}
+
+// recordTLS copies the negotiated parameters into measured.
+func recordTLS(measured *measuredResponse, cs tls.ConnectionState) {
+	measured.TLSVersion = tls.VersionName(cs.Version)
+	measured.CipherSuite = tls.CipherSuiteName(cs.CipherSuite)
+	if len(cs.PeerCertificates) > 0 {
+		measured.CertExpiry = cs.PeerCertificates[0].NotAfter
+	}
+}
+
+// tlsSummary describes the TLS session of a single response, or returns
+// "" for plain HTTP.
+func tlsSummary(measured measuredResponse) string {
+	if measured.TLSVersion == "" {
+		return ""
+	}
+	summary := measured.TLSVersion + ", " + measured.CipherSuite
+	if !measured.CertExpiry.IsZero() {
+		summary += fmt.Sprintf(", certificate expires %s (in %s)",
+			measured.CertExpiry.Format(time.RFC3339), time.Until(measured.CertExpiry).Round(time.Hour))
+	}
+	return summary
+}
@@ -0,0 +1,310 @@
+// cmd/trace.go
+//
+// W3C trace context propagation and span export. When an OTLP endpoint
+// or a trace file is given, every request gets its own traceparent
+// header and is recorded as a client span. Spans are exported once the
+// command finishes, as OTLP/HTTP JSON.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var tracingFlags struct {
+	OTLPEndpoint string
+	TraceFile    string
+	ServiceName  string
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&tracingFlags.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces")
+	rootCmd.PersistentFlags().StringVar(&tracingFlags.TraceFile, "trace-file", "", "Write recorded spans to a JSON file")
+	rootCmd.PersistentFlags().StringVar(&tracingFlags.ServiceName, "service-name", "hpgo", "service.name reported with exported spans")
+}
+
+func tracingEnabled() bool {
+	return tracingFlags.OTLPEndpoint != "" || tracingFlags.TraceFile != ""
+}
+
+type spanEvent struct {
+	Name string
+	Time time.Time
+}
+
+// span is a single client request. A nil *span is valid and records
+// nothing, so callers don't need to check whether tracing is on.
+type span struct {
+	mu       sync.Mutex
+	traceID  string
+	spanID   string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	status   int
+	events   []spanEvent
+	errorMsg string
+	done     bool
+}
+
+var recordedSpans struct {
+	sync.Mutex
+	list []*span
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startSpan opens a span for req and sets its traceparent header.
+func startSpan(req *http.Request) *span {
+	if !tracingEnabled() {
+		return nil
+	}
+	sp := &span{
+		traceID: randomHex(16),
+		spanID:  randomHex(8),
+		name:    req.Method,
+		start:   time.Now(),
+		attrs: map[string]string{
+			"http.request.method": req.Method,
+			"url.full":            req.URL.String(),
+			"server.address":      req.URL.Hostname(),
+		},
+	}
+	req.Header.Set("traceparent", fmt.Sprintf("00-%s-%s-01", sp.traceID, sp.spanID))
+	return sp
+}
+
+func (sp *span) event(name string) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	sp.events = append(sp.events, spanEvent{Name: name, Time: time.Now()})
+	sp.mu.Unlock()
+}
+
+// attachTo records the response on the span and ends it once the
+// response body is closed, so the span covers the full download.
+func (sp *span) attachTo(resp *http.Response) {
+	if sp == nil {
+		return
+	}
+	resp.Body = &spanBody{ReadCloser: resp.Body, sp: sp, resp: resp}
+}
+
+func (sp *span) finish(resp *http.Response, err error) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	if sp.done {
+		sp.mu.Unlock()
+		return
+	}
+	sp.done = true
+	sp.end = time.Now()
+	if resp != nil {
+		sp.status = resp.StatusCode
+		sp.attrs["network.protocol.version"] = resp.Proto
+	}
+	if err != nil {
+		sp.errorMsg = err.Error()
+	}
+	sp.mu.Unlock()
+
+	recordedSpans.Lock()
+	recordedSpans.list = append(recordedSpans.list, sp)
+	recordedSpans.Unlock()
+}
+
+type spanBody struct {
+	io.ReadCloser
+	sp   *span
+	resp *http.Response
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.sp.finish(b.resp, nil)
+	return err
+}
+
+// OTLP/HTTP JSON encoding, see opentelemetry-proto's trace.proto.
+type otlpExport struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Events            []otlpEvent     `json:"events,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string `json:"timeUnixNano"`
+	Name         string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindClient = 3
+	otlpStatusUnset    = 0
+	otlpStatusError    = 2
+)
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttr(key string, value int) otlpAttribute {
+	v := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &v}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (sp *span) otlp() otlpSpan {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	out := otlpSpan{
+		TraceID:           sp.traceID,
+		SpanID:            sp.spanID,
+		Name:              sp.name,
+		Kind:              otlpSpanKindClient,
+		StartTimeUnixNano: unixNano(sp.start),
+		EndTimeUnixNano:   unixNano(sp.end),
+	}
+	for key, value := range sp.attrs {
+		out.Attributes = append(out.Attributes, stringAttr(key, value))
+	}
+	for _, ev := range sp.events {
+		out.Events = append(out.Events, otlpEvent{TimeUnixNano: unixNano(ev.Time), Name: ev.Name})
+	}
+
+	if sp.status > 0 {
+		out.Attributes = append(out.Attributes, intAttr("http.response.status_code", sp.status))
+	}
+	// The HTTP semantic conventions mark client spans as errors from 400
+	// up and leave the status unset otherwise.
+	switch {
+	case sp.errorMsg != "":
+		out.Status = otlpStatus{Code: otlpStatusError, Message: sp.errorMsg}
+	case sp.status >= 400:
+		out.Status = otlpStatus{Code: otlpStatusError}
+	default:
+		out.Status = otlpStatus{Code: otlpStatusUnset}
+	}
+	return out
+}
+
+// flushSpans exports every finished span to the configured endpoint
+// and/or file. It runs once the command returns, exits or panics.
+func flushSpans() {
+	if !tracingEnabled() {
+		return
+	}
+	recordedSpans.Lock()
+	list := recordedSpans.list
+	recordedSpans.list = nil
+	recordedSpans.Unlock()
+	if len(list) == 0 {
+		return
+	}
+
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "hpgo"}}
+	for _, sp := range list {
+		scope.Spans = append(scope.Spans, sp.otlp())
+	}
+	export := otlpExport{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{stringAttr("service.name", tracingFlags.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}}
+
+	data, err := json.Marshal(export)
+	if err == nil {
+		data = []byte(maskSecrets(string(data)))
+	}
+	if err != nil {
+		fmt.Println("Error encoding spans:", err)
+		return
+	}
+
+	if tracingFlags.TraceFile != "" {
+		if err := os.WriteFile(tracingFlags.TraceFile, data, 0644); err != nil {
+			fmt.Println("Error writing trace file:", err)
+		} else {
+			fmt.Printf("Wrote %d spans to %s\n", len(list), tracingFlags.TraceFile)
+		}
+	}
+
+	if tracingFlags.OTLPEndpoint != "" {
+		resp, err := http.Post(tracingFlags.OTLPEndpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			fmt.Println("Error exporting spans:", err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Printf("Error exporting spans: %s %s\n", resp.Status, body)
+			return
+		}
+		fmt.Printf("Exported %d spans to %s\n", len(list), tracingFlags.OTLPEndpoint)
+	}
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestSpanStatus(t *testing.T) {
+	tests := []struct {
+		status   int
+		errorMsg string
+		want     int
+	}{
+		{200, "", otlpStatusUnset},
+		{304, "", otlpStatusUnset},
+		{404, "", otlpStatusError},
+		{503, "", otlpStatusError},
+		{0, "connection refused", otlpStatusError},
+		{0, "", otlpStatusUnset},
+	}
+	for _, tt := range tests {
+		sp := &span{status: tt.status, errorMsg: tt.errorMsg, attrs: map[string]string{}}
+		if got := sp.otlp().Status.Code; got != tt.want {
+			t.Errorf("status %d, error %q: got code %d, want %d", tt.status, tt.errorMsg, got, tt.want)
+		}
+	}
+}
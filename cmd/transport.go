@@ -0,0 +1,319 @@
+// cmd/transport.go
+//
+// Builds the transport every command sends its requests through, so
+// protocol and connection options behave the same in get, stress,
+// execute and friends.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+type transportConfig struct {
+	HTTP2  bool
+	H2C    bool
+	HTTP11 bool
+	HTTP3  bool
+
+	KeepAlive       bool
+	NoKeepAlive     bool
+	MaxConnsPerHost int
+	IdleConnTimeout time.Duration
+	Compressed      bool
+
+	TLS   tlsOptions
+	Proxy string
+
+	UnixSocket string
+	ConnectTo  []string
+
+	Resolve   []string
+	DNSServer string
+	DNSCache  bool
+
+	Auth      authOptions
+	Signing   signingOptions
+	Cookies   cookieOptions
+	Redirects redirectOptions
+
+	Headers []string
+	Timeout time.Duration
+}
+
+var transportFlags transportConfig
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.HTTP2, "http2", false, "Force HTTP/2 for https URLs")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.H2C, "h2c", false, "Use cleartext HTTP/2 with prior knowledge")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.HTTP11, "http1.1", false, "Force HTTP/1.1")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.HTTP3, "http3", false, "Use HTTP/3 over QUIC (https URLs only)")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.KeepAlive, "keepalive", true, "Reuse connections between requests")
+	rootCmd.PersistentFlags().BoolVar(&transportFlags.NoKeepAlive, "no-keepalive", false, "Open a new connection for every request")
+	rootCmd.PersistentFlags().IntVar(&transportFlags.MaxConnsPerHost, "max-conns-per-host", 0, "Limit on connections per host, 0 for no limit")
+	rootCmd.PersistentFlags().DurationVar(&transportFlags.IdleConnTimeout, "idle-timeout", 90*time.Second, "How long an idle connection is kept for reuse")
+}
+
+func (cfg transportConfig) keepAlive() bool {
+	return cfg.KeepAlive && !cfg.NoKeepAlive
+}
+
+func (cfg transportConfig) validate() error {
+	forced := 0
+	for _, set := range []bool{cfg.HTTP2, cfg.H2C, cfg.HTTP11, cfg.HTTP3} {
+		if set {
+			forced++
+		}
+	}
+	if forced > 1 {
+		return fmt.Errorf("--http2, --h2c, --http1.1 and --http3 are mutually exclusive")
+	}
+	if cfg.Proxy != "" && (cfg.H2C || cfg.HTTP3) {
+		return fmt.Errorf("--proxy can't be combined with --h2c or --http3")
+	}
+	if cfg.UnixSocket != "" && cfg.HTTP3 {
+		return fmt.Errorf("--unix-socket can't be combined with --http3")
+	}
+	if !cfg.keepAlive() && (cfg.H2C || cfg.HTTP3) {
+		return fmt.Errorf("--no-keepalive can't be combined with --h2c or --http3, they multiplex requests over one connection")
+	}
+	return nil
+}
+
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// connStats counts connections dialed and closed by every transport
+// built in this process. Reuse is tracked per request, see GotConn in
+// measureRequest.
+var connStats struct {
+	Opened atomic.Int64
+	Closed atomic.Int64
+}
+
+type countedConn struct {
+	net.Conn
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { connStats.Closed.Add(1) })
+	return c.Conn.Close()
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func countDials(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		connStats.Opened.Add(1)
+		return &countedConn{Conn: conn}, nil
+	}
+}
+
+// idleClosers are the network transports built so far, so their idle
+// connections can be closed from outside the layers wrapping them.
+var idleClosers struct {
+	sync.Mutex
+	list []interface{ CloseIdleConnections() }
+}
+
+func closeIdleConnections() {
+	idleClosers.Lock()
+	defer idleClosers.Unlock()
+	for _, closer := range idleClosers.list {
+		closer.CloseIdleConnections()
+	}
+}
+
+func printConnectionStats() {
+	fmt.Println("Connections Opened:", connStats.Opened.Load())
+	fmt.Println("Connections Closed:", connStats.Closed.Load())
+}
+
+// newTransport builds a RoundTripper from cfg: a shared transport plus
+// the session layers on top of it.
+func newTransport(cfg transportConfig) (http.RoundTripper, error) {
+	shared, err := newSharedTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.sessionTransport(shared)
+}
+
+// newSharedTransport is the protocol transport from newBaseTransport,
+// wrapped in the request-level layers that can be shared between
+// sessions. Signing sits closest to the wire so it covers headers added
+// by the others.
+func newSharedTransport(cfg transportConfig) (http.RoundTripper, error) {
+	if cfg.Signing.AWSSigV4 != "" && cfg.Auth != (authOptions{}) {
+		return nil, fmt.Errorf("--aws-sigv4 sets the Authorization header and can't be combined with other auth")
+	}
+	base, err := newBaseTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := cfg.Signing.wrap(base)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Auth.wrap(withCompression(signed, cfg.Compressed))
+}
+
+// sessionTransport adds the layers that belong to one session (a
+// command run, or one execute line) on top of shared: a cookie jar of
+// its own when cookies are on, redirect following outside of it so
+// every hop sends and stores cookies, --print-curl above that so a
+// request prints once whatever its redirects, and the default headers
+// and timeout outermost so the timeout covers the whole redirect chain.
+func (cfg transportConfig) sessionTransport(shared http.RoundTripper) (http.RoundTripper, error) {
+	rt := shared
+	if cfg.Cookies.enabled() {
+		jar, err := cfg.Cookies.newJar()
+		if err != nil {
+			return nil, err
+		}
+		rt = jar.wrap(rt)
+	}
+	return withDefaults(withPrintCurl(cfg.Redirects.wrap(rt), cfg), cfg)
+}
+
+// newBaseTransport builds the transport that actually talks to the
+// network. Without any protocol flag it behaves like
+// http.DefaultTransport.
+func newBaseTransport(cfg transportConfig) (http.RoundTripper, error) {
+	if profileErr != nil {
+		return nil, profileErr
+	}
+	rt, err := newProtocolTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		idleClosers.Lock()
+		idleClosers.list = append(idleClosers.list, closer)
+		idleClosers.Unlock()
+	}
+	return rt, nil
+}
+
+func newProtocolTransport(cfg transportConfig) (http.RoundTripper, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	tlsCfg, err := cfg.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.HTTP3 {
+		return newHTTP3Transport(cfg, tlsCfg)
+	}
+
+	baseDial, err := cfg.dialer()
+	if err != nil {
+		return nil, err
+	}
+	dial := countDials(baseDial)
+
+	if cfg.H2C {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+			IdleConnTimeout:    cfg.IdleConnTimeout,
+			DisableCompression: true,
+		}, nil
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = dial
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxyURL(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		configureProxy(tr, proxyURL)
+	}
+	if tlsCfg != nil {
+		// A custom TLS config turns off HTTP/2 unless asked for.
+		tr.TLSClientConfig = tlsCfg
+		tr.ForceAttemptHTTP2 = true
+	}
+	tr.DisableKeepAlives = !cfg.keepAlive()
+	tr.DisableCompression = true
+	tr.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.MaxConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		tr.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	switch {
+	case cfg.HTTP2:
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		tr.ForceAttemptHTTP2 = true
+		tr.TLSClientConfig.NextProtos = []string{http2.NextProtoTLS}
+		return requireHTTP2{tr}, nil
+	case cfg.HTTP11:
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return tr, nil
+}
+
+// requireHTTP2 fails responses that fell back to HTTP/1.x, since the
+// standard transport still offers http/1.1 during ALPN.
+type requireHTTP2 struct {
+	rt http.RoundTripper
+}
+
+func (t requireHTTP2) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" && resp.ProtoMajor != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s did not negotiate HTTP/2 (got %s)", req.URL.Host, resp.Proto)
+	}
+	return resp, nil
+}
+
+func (t requireHTTP2) CloseIdleConnections() {
+	t.rt.(*http.Transport).CloseIdleConnections()
+}
+
+// newClient wraps newTransport in an http.Client. Redirects are handled
+// by the transport, so the client itself never follows them.
+func newClient(cfg transportConfig) (*http.Client, error) {
+	tr, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
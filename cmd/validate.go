@@ -0,0 +1,63 @@
+// cmd/validate.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [fileName]",
+	Short: "Checks every line of a txt file the way 'execute' reads it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName := args[0]
+		if !strings.HasSuffix(fileName, ".txt") {
+			fileName = fileName + ".txt"
+		}
+
+		filePath, err := suitePath(fileName)
+		if err != nil {
+			fmt.Println(err)
+			exit(1)
+		}
+
+		fileLines, err := readFileLines(filePath)
+		if os.IsNotExist(err) {
+			fmt.Println("File does not exist:", fileName)
+			exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error reading file:", err)
+			exit(1)
+		}
+
+		valid, invalid := 0, 0
+		for i, line := range fileLines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			parsed, err := parseLine(line)
+			if err == nil && parsed.Schema != "" {
+				_, err = lineSchema(filePath, parsed.Schema)
+			}
+			if err != nil {
+				fmt.Printf("%s:%d: %v\n", fileName, i+1, err)
+				invalid++
+				continue
+			}
+			valid++
+		}
+		fmt.Printf("%s: %d valid, %d invalid\n", fileName, valid, invalid)
+		if invalid > 0 {
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,100 @@
+// cmd/workspace.go
+//
+// The workspace is the folder holding the .txt suites. It's looked up in
+// this order:
+//
+//  1. --workspace
+//  2. $HPGO_HOME
+//  3. a .hpgo folder in the current directory or any parent
+//  4. hpgo in the user config directory (~/.config/hpgo on Linux)
+//
+// Suites used to live in ./executable. That folder isn't read any more,
+// hpgo only warns when it finds one so its suites can be moved.
+//
+// Suites can be grouped in nested folders, e.g. "payments/smoke".
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var workspaceFlag string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&workspaceFlag, "workspace", "", "Folder holding the suite files (default $HPGO_HOME, .hpgo/ or the user config dir)")
+}
+
+// findUp returns the first dir/name found walking up from dir.
+func findUp(dir, name string) (string, bool) {
+	for {
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func workspaceDir() (string, error) {
+	if workspaceFlag != "" {
+		return workspaceFlag, nil
+	}
+	if home := os.Getenv("HPGO_HOME"); home != "" {
+		return home, nil
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if dir, ok := findUp(cwd, ".hpgo"); ok {
+			return dir, nil
+		}
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no workspace found, use --workspace or HPGO_HOME: %w", err)
+	}
+	dir := filepath.Join(configDir, "hpgo")
+	warnOldWorkspace(dir)
+	return dir, nil
+}
+
+var oldWorkspaceOnce sync.Once
+
+// warnOldWorkspace points out suites left in ./executable, which older
+// versions used as the workspace.
+func warnOldWorkspace(dir string) {
+	oldWorkspaceOnce.Do(func() {
+		if suites, _ := filepath.Glob(filepath.Join("executable", "*.txt")); len(suites) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: ./executable is no longer the workspace, using %s. "+
+				"Move its suites there, or pass --workspace executable.\n", dir)
+		}
+	})
+}
+
+// suitePath maps a suite name such as "smoke" or "payments/smoke" to its
+// .txt file in the workspace. Names can't climb out of the workspace.
+func suitePath(name string) (string, error) {
+	if !strings.HasSuffix(name, ".txt") {
+		name = name + ".txt"
+	}
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid file name %q: must be relative to the workspace", name)
+	}
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return "", fmt.Errorf("invalid file name %q: \"..\" is not allowed", name)
+		}
+	}
+	dir, err := workspaceDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceDir(t *testing.T) {
+	root := t.TempDir()
+	config := filepath.Join(root, "config")
+	project := filepath.Join(root, "project")
+	nested := filepath.Join(project, "a", "b")
+	plain := filepath.Join(root, "plain")
+	os.MkdirAll(filepath.Join(project, ".hpgo"), 0755)
+	os.MkdirAll(nested, 0755)
+	// Suites left where older versions kept them aren't picked up.
+	os.MkdirAll(filepath.Join(plain, "executable"), 0755)
+	os.WriteFile(filepath.Join(plain, "executable", "smoke.txt"), nil, 0644)
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	defer func(saved string) { workspaceFlag = saved }(workspaceFlag)
+
+	tests := []struct {
+		flag, env, dir string
+		want           string
+	}{
+		{"flag", "env", nested, "flag"},
+		{"", "env", nested, "env"},
+		{"", "", nested, filepath.Join(project, ".hpgo")},
+		{"", "", project, filepath.Join(project, ".hpgo")},
+		{"", "", plain, filepath.Join(config, "hpgo")},
+	}
+	for _, tt := range tests {
+		workspaceFlag = tt.flag
+		t.Setenv("HPGO_HOME", tt.env)
+		if err := os.Chdir(tt.dir); err != nil {
+			t.Fatal(err)
+		}
+		got, err := workspaceDir()
+		if err != nil || got != tt.want {
+			t.Errorf("--workspace %q HPGO_HOME %q in %s: got %s, %v, want %s", tt.flag, tt.env, tt.dir, got, err, tt.want)
+		}
+	}
+}